@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/geobeau/k8s-ephemeral-resources/controller"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// AdmitNamespace serves the ValidatingAdmissionWebhook protecting the
+// lifecycle labels of instance namespaces against manual modification
+func AdmitNamespace(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	review := admissionv1.AdmissionReview{}
+	err := json.NewDecoder(r.Body).Decode(&review)
+	if err != nil {
+		wrapError(err, w, http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		wrapError(errors.New("Invalid AdmissionReview: no request"), w, http.StatusBadRequest)
+		return
+	}
+	review.Response = c.ReviewNamespaceAdmission(review.Request)
+	json.NewEncoder(w).Encode(review)
+}
+
+// MutateWorkload serves the MutatingAdmissionWebhook stamping the instance
+// labels onto every object created inside an ephemeral namespace
+func MutateWorkload(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	review := admissionv1.AdmissionReview{}
+	err := json.NewDecoder(r.Body).Decode(&review)
+	if err != nil {
+		wrapError(err, w, http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		wrapError(errors.New("Invalid AdmissionReview: no request"), w, http.StatusBadRequest)
+		return
+	}
+	review.Response = c.MutateWorkloadAdmission(review.Request)
+	json.NewEncoder(w).Encode(review)
+}
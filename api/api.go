@@ -2,30 +2,73 @@ package api
 
 import (
 	"net/http"
+	"crypto/subtle"
 	"encoding/json"
+	"fmt"
 	"log"
 	"errors"
+	"strconv"
+	"time"
 
+	"github.com/geobeau/k8s-ephemeral-resources/clock"
 	"github.com/geobeau/k8s-ephemeral-resources/controller"
+	"github.com/geobeau/k8s-ephemeral-resources/maintenance"
 
 	"github.com/gorilla/mux"
 )
 
 type requestData struct {
-	Owner string
+	Owner        string
+	Alias        string
+	Fixture      string
+	StorageSize  string
+	StorageClass string
+	TTLFrom           string
+	EstimatedDuration string
+	Lifecycle         string
+	Tier              string
+	Params            map[string]interface{}
+	Manifests         string
 }
 
-// GetResource display all instances for a type of resource
+// GetResource display all instances for a type of resource with the template
+// version each was created from. With ?limit= the listing is paginated and
+// answers an opaque continue token for the next page
 func GetResource(w http.ResponseWriter, r *http.Request, c controller.Controller) {
 	resourceName := mux.Vars(r)["resource"]
-	resource := c.Resources[resourceName]
-	log.Println(resource)
-	json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+	if authorized(w, r, c, "list", resourceName) != true {
+		return
+	}
+
+	if r.URL.Query().Get("limit") != "" {
+		limit, err := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+		if err != nil || limit <= 0 {
+			wrapError(errors.New("limit must be a positive integer"), w, http.StatusBadRequest)
+			return
+		}
+		instances, continueToken, err := c.ListInstancesPage(resourceName, limit, r.URL.Query().Get("continue"))
+		if err != nil {
+			wrapError(err, w, http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"instances": instances, "continue": continueToken})
+		return
+	}
+
+	instances, err := c.ListInstances(resourceName)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(instances)
 }
 
 // CreateResource create a new instance of a resource
 func CreateResource(w http.ResponseWriter, r *http.Request,  c controller.Controller) {
 	resourceName := mux.Vars(r)["resource"]
+	if authorized(w, r, c, "create", resourceName) != true {
+		return
+	}
 	if r.Body == nil {
 		wrapError(errors.New("Invalid JSON. Please provide owner like: {\"Owner\":\"resourceowner\"}"), w, http.StatusBadRequest)
 		return
@@ -36,8 +79,52 @@ func CreateResource(w http.ResponseWriter, r *http.Request,  c controller.Contro
 		wrapError(errors.New("Invalid JSON. Please provide owner like: {\"Owner\":\"resourceowner\"}"), w, http.StatusBadRequest)
 		return
 	}
-	instance, err := c.CreateNewInstance(resourceName, requestData.Owner)
+	identity, err := c.Authenticate(r)
+	if err != nil {
+		wrapError(err, w, http.StatusUnauthorized)
+		return
+	}
+	// With OIDC the owner comes from the verified token, the body cannot
+	// impersonate someone else
+	if c.OIDCEnabled() {
+		requestData.Owner = identity.Username
+	}
+	// The tenant is resolved from the same verified identity, not from a
+	// header the caller controls
+	tenant, err := c.TenantForIdentity(identity.Username)
+	if err != nil {
+		wrapError(err, w, http.StatusForbidden)
+		return
+	}
+
+	paramErrors := c.ValidateParams(resourceName, requestData.Params)
+	if len(paramErrors) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": paramErrors})
+		return
+	}
+
+	options := controller.CreateOptions{
+		Owner:        requestData.Owner,
+		Tenant:       tenant.Name,
+		Alias:        requestData.Alias,
+		Fixture:      requestData.Fixture,
+		StorageSize:  requestData.StorageSize,
+		StorageClass: requestData.StorageClass,
+		TTLFrom:           requestData.TTLFrom,
+		EstimatedDuration: requestData.EstimatedDuration,
+		Lifecycle:         requestData.Lifecycle,
+		Tier:              requestData.Tier,
+		Params:            requestData.Params,
+		Manifests:         requestData.Manifests,
+	}
+	instance, err := c.CreateNewInstance(resourceName, options)
 	if err != nil {
+		_, frozen := err.(*controller.FrozenError)
+		if frozen {
+			wrapError(err, w, http.StatusForbidden)
+			return
+		}
 		wrapError(err, w, http.StatusInternalServerError)
 		return
 	}
@@ -45,11 +132,739 @@ func CreateResource(w http.ResponseWriter, r *http.Request,  c controller.Contro
 	json.NewEncoder(w).Encode(response)
 }
 
-// DeleteResource delete an instnace of a resource
+// RenderResource returns the fully rendered manifests of a resource without
+// creating anything
+func RenderResource(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	if authorized(w, r, c, "render", resourceName) != true {
+		return
+	}
+	requestData := requestData{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&requestData)
+	}
+	options := controller.CreateOptions{
+		Owner:        requestData.Owner,
+		Fixture:      requestData.Fixture,
+		StorageSize:  requestData.StorageSize,
+		StorageClass: requestData.StorageClass,
+	}
+	manifests, err := c.RenderInstance(resourceName, options)
+	if err != nil {
+		wrapError(err, w, http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(manifests)
+}
+
+// DeleteResource marks an instance of a resource for deletion. The instance
+// is actually removed once the undo window has passed
 func DeleteResource(w http.ResponseWriter, r *http.Request,  c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	if authorized(w, r, c, "delete", resourceName) != true {
+		return
+	}
+	if ownsOrAdmin(w, r, c, resourceName, resourceID) != true {
+		return
+	}
+	deleteAt, err := c.MarkInstanceForDeletion(resourceName, resourceID)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"ok": "true", "deleteAt": deleteAt.Format(time.RFC3339)})
+}
+
+// ResizeResource adjusts the CPU/memory of an instance within the resource
+// bounds
+func ResizeResource(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	if authorized(w, r, c, "resize", resourceName) != true {
+		return
+	}
+	resizeRequest := controller.ResizeRequest{}
+	err := json.NewDecoder(r.Body).Decode(&resizeRequest)
+	if err != nil {
+		wrapError(errors.New("Invalid JSON. Please provide quantities like: {\"CPULimit\":\"2\"}"), w, http.StatusBadRequest)
+		return
+	}
+	err = c.ResizeInstance(resourceName, resourceID, resizeRequest)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+}
+
+// InjectFault injects a fault into an instance of a chaos-enabled resource
+func InjectFault(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	if authorized(w, r, c, "inject", resourceName) != true {
+		return
+	}
+	faultRequest := struct{ Fault string }{}
+	err := json.NewDecoder(r.Body).Decode(&faultRequest)
+	if err != nil || faultRequest.Fault == "" {
+		wrapError(errors.New("Invalid JSON. Please provide a fault like: {\"Fault\":\"kill-pod\"}"), w, http.StatusBadRequest)
+		return
+	}
+	err = c.InjectFault(resourceName, resourceID, faultRequest.Fault)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+}
+
+// GetResourceDiagnostics displays the recent events and pod statuses of an
+// instance
+func GetResourceDiagnostics(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	diagnostics, err := c.InstanceDiagnostics(resourceName, resourceID)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(diagnostics)
+}
+
+// GetResourceOutputs displays the terraform outputs of an instance as its
+// connection details. The outputs typically carry endpoints and credentials,
+// so they are only handed to the owners of the instance
+func GetResourceOutputs(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	if authorized(w, r, c, "get", resourceName) != true {
+		return
+	}
+	if ownsOrAdmin(w, r, c, resourceName, resourceID) != true {
+		return
+	}
+	outputs, err := c.TerraformOutputs(resourceName, resourceID)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(outputs)
+}
+
+// GetInstances lists instance namespaces. Without parameters the whole
+// inventory is returned, as JSON, CSV (?output=csv or Accept: text/csv) or
+// an aligned table (?output=table). With ?state=stuck only the namespaces
+// stuck in Terminating are returned, with ?tag= the instances carrying the
+// tag across all resources
+func GetInstances(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	tag := r.URL.Query().Get("tag")
+	if tag != "" {
+		tagged, err := c.InstancesByTag(tag)
+		if err != nil {
+			wrapError(err, w, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(tagged)
+		return
+	}
+	if r.URL.Query().Get("state") == "stuck" {
+		stuck, err := c.StuckInstances()
+		if err != nil {
+			wrapError(err, w, http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(stuck)
+		return
+	}
+	entries, err := c.InventoryEntries()
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	writeInventory(w, r, entries)
+}
+
+// GetCatalog returns the loaded resource catalog, so portals and CLIs can
+// discover what can be requested
+func GetCatalog(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	json.NewEncoder(w).Encode(c.Catalog())
+}
+
+// TagResource adds a tag on an instance
+func TagResource(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	tag := mux.Vars(r)["tag"]
+	if authorized(w, r, c, "tag", resourceName) != true {
+		return
+	}
+	err := c.TagInstance(resourceName, resourceID, tag)
+	if err != nil {
+		wrapError(err, w, http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode("tagged")
+}
+
+// UntagResource removes a tag from an instance
+func UntagResource(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	tag := mux.Vars(r)["tag"]
+	if authorized(w, r, c, "tag", resourceName) != true {
+		return
+	}
+	err := c.UntagInstance(resourceName, resourceID, tag)
+	if err != nil {
+		wrapError(err, w, http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode("untagged")
+}
+
+// PatchOwners grants or revokes co-owners on an instance, so environments
+// handed across timezones can be extended and deleted by several people
+func PatchOwners(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	// Only an existing owner can grant ownership, otherwise anyone could
+	// add themselves and walk through every ownership gate afterwards
+	if authorized(w, r, c, "owners", resourceName) != true {
+		return
+	}
+	if ownsOrAdmin(w, r, c, resourceName, resourceID) != true {
+		return
+	}
+	ownersRequest := struct {
+		Add    []string
+		Remove []string
+	}{}
+	err := json.NewDecoder(r.Body).Decode(&ownersRequest)
+	if err != nil {
+		wrapError(errors.New("Invalid JSON. Please provide owners like: {\"Add\":[\"someowner\"]}"), w, http.StatusBadRequest)
+		return
+	}
+	owners, err := c.UpdateOwners(resourceName, resourceID, ownersRequest.Add, ownersRequest.Remove)
+	if err != nil {
+		wrapError(err, w, http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"owners": owners})
+}
+
+// GetOwners lists the owner and the co-owners of an instance
+func GetOwners(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	owners, err := c.InstanceOwners(resourceName, resourceID)
+	if err != nil {
+		wrapError(err, w, http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"owners": owners})
+}
+
+// GetHistory displays the lifecycle timeline of an instance: when it was
+// requested, provisioned, ready, extended and so on
+func GetHistory(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	history, err := c.PhaseHistory(resourceName, resourceID)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"history": history})
+}
+
+// ExtendResource pushes back the expiration date of an instance, possibly
+// leaving the request pending for an admin approval
+func ExtendResource(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	if authorized(w, r, c, "extend", resourceName) != true {
+		return
+	}
+	if ownsOrAdmin(w, r, c, resourceName, resourceID) != true {
+		return
+	}
+	extendRequest := struct{ Duration string }{}
+	err := json.NewDecoder(r.Body).Decode(&extendRequest)
+	if err != nil || extendRequest.Duration == "" {
+		wrapError(errors.New("Invalid JSON. Please provide a duration like: {\"Duration\":\"2h\"}"), w, http.StatusBadRequest)
+		return
+	}
+	pending, err := c.ExtendInstance(resourceName, resourceID, extendRequest.Duration)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	if pending {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"ok": "true", "status": "pending approval"})
+		return
+	}
 	json.NewEncoder(w).Encode(map[string]bool{"ok": true})
 }
 
+// GetPendingExtensions lists the extension requests waiting for an admin
+func GetPendingExtensions(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	if adminOnly(w, r, c, "extensions") != true {
+		return
+	}
+	pending, err := c.PendingExtensions()
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(pending)
+}
+
+// ResolveExtension approves or denies a pending extension request. The
+// approval is an admin decision, otherwise owners would wave their own
+// requests through
+func ResolveExtension(w http.ResponseWriter, r *http.Request, c controller.Controller, approve bool) {
+	resourceID := mux.Vars(r)["resourceId"]
+	if adminOnly(w, r, c, "extensions") != true {
+		return
+	}
+	err := c.ResolveExtension(resourceID, approve)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+}
+
+// FreezeResource disables the creation of new instances of a resource type
+func FreezeResource(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	if adminOnly(w, r, c, "freeze") != true {
+		return
+	}
+	freezeRequest := struct{ Reason string }{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&freezeRequest)
+	}
+	err := c.FreezeResource(resourceName, freezeRequest.Reason)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+}
+
+// UnfreezeResource re-enables the creation of new instances of a resource
+// type
+func UnfreezeResource(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	if adminOnly(w, r, c, "freeze") != true {
+		return
+	}
+	err := c.UnfreezeResource(resourceName)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+}
+
+// SetMaintenance pauses every automatic action cluster-wide: cleanup
+// deletions, suspensions, retaliation. Meant for apiserver upgrades when
+// watches get flaky and false positives spike. Pausing (or resuming
+// mid-incident) is an admin decision
+func SetMaintenance(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	if adminOnly(w, r, c, "maintenance") != true {
+		return
+	}
+	setMaintenance(w, r)
+}
+
+// ResetMaintenance resumes the automatic actions
+func ResetMaintenance(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	if adminOnly(w, r, c, "maintenance") != true {
+		return
+	}
+	resetMaintenance(w, r)
+}
+
+// SetMaintenanceWithToken is the watch subcommand variant: there is no
+// controller to authenticate against, so the caller must present the token
+// given on the command line
+func SetMaintenanceWithToken(w http.ResponseWriter, r *http.Request, token string) {
+	if maintenanceTokenValid(w, r, token) != true {
+		return
+	}
+	setMaintenance(w, r)
+}
+
+// ResetMaintenanceWithToken resumes the automatic actions on the watch
+// subcommand
+func ResetMaintenanceWithToken(w http.ResponseWriter, r *http.Request, token string) {
+	if maintenanceTokenValid(w, r, token) != true {
+		return
+	}
+	resetMaintenance(w, r)
+}
+
+// maintenanceTokenValid verifies the maintenance token of the request,
+// rejecting every request when no token was configured
+func maintenanceTokenValid(w http.ResponseWriter, r *http.Request, token string) bool {
+	if token == "" {
+		wrapError(errors.New("no maintenance token is configured"), w, http.StatusForbidden)
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+		wrapError(errors.New("invalid maintenance token"), w, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// setMaintenance enables the maintenance mode once the caller is vetted
+func setMaintenance(w http.ResponseWriter, r *http.Request) {
+	request := struct{ Reason string }{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&request)
+	}
+	log.Printf("Entering maintenance mode (reason: %s)", request.Reason)
+	maintenance.Enable(request.Reason)
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "maintenance": true})
+}
+
+// resetMaintenance disables the maintenance mode once the caller is vetted
+func resetMaintenance(w http.ResponseWriter, r *http.Request) {
+	log.Println("Leaving maintenance mode")
+	maintenance.Disable()
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "maintenance": false})
+}
+
+// GetMaintenance displays the maintenance mode
+func GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	active, reason, since := maintenance.Status()
+	status := map[string]interface{}{"maintenance": active}
+	if active {
+		status["reason"] = reason
+		status["since"] = since.Format(time.RFC3339)
+	}
+	json.NewEncoder(w).Encode(status)
+}
+
+// SetClock switches the manager to a simulated clock, shifted by an offset
+// and optionally accelerated, for staging environments exercising the
+// lifecycle without waiting. A large enough offset makes every instance look
+// expired, so the endpoint is restricted to admins and to configurations
+// that opted in
+func SetClock(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	if c.ClockSimulationAllowed() != true {
+		wrapError(errors.New("clock simulation is not enabled in the configuration"), w, http.StatusForbidden)
+		return
+	}
+	if adminOnly(w, r, c, "clock") != true {
+		return
+	}
+	clockRequest := struct {
+		Offset string
+		Factor float64
+	}{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&clockRequest)
+	}
+	offset := time.Duration(0)
+	if clockRequest.Offset != "" {
+		parsed, err := time.ParseDuration(clockRequest.Offset)
+		if err != nil {
+			wrapError(err, w, http.StatusBadRequest)
+			return
+		}
+		offset = parsed
+	}
+	log.Printf("Switching to a simulated clock (offset: %s, factor: %f)", offset, clockRequest.Factor)
+	clock.Simulate(offset, clockRequest.Factor)
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "now": clock.Now()})
+}
+
+// ResetClock switches the manager back to the real clock
+func ResetClock(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	if c.ClockSimulationAllowed() != true {
+		wrapError(errors.New("clock simulation is not enabled in the configuration"), w, http.StatusForbidden)
+		return
+	}
+	if adminOnly(w, r, c, "clock") != true {
+		return
+	}
+	log.Println("Switching back to the real clock")
+	clock.Reset()
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "now": clock.Now()})
+}
+
+// DrainResource marks every instance of a resource type for deletion
+func DrainResource(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	if adminOnly(w, r, c, "drain") != true {
+		return
+	}
+	if confirmed(w, r, c, "drain/"+resourceName) != true {
+		return
+	}
+	drained, err := c.DrainResource(resourceName)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "drained": drained})
+}
+
+// GetOutdatedInstances lists the instances created from an older version of
+// the resource definition
+func GetOutdatedInstances(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	outdated, err := c.OutdatedInstances(resourceName)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(outdated)
+}
+
+// RecreateOutdatedInstances replaces a batch of instances created from an
+// older template version
+func RecreateOutdatedInstances(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	if adminOnly(w, r, c, "recreate") != true {
+		return
+	}
+	if confirmed(w, r, c, "recreate/"+resourceName) != true {
+		return
+	}
+	recreateRequest := struct{ BatchSize int }{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&recreateRequest)
+	}
+	recreated, err := c.RecreateOutdated(resourceName, recreateRequest.BatchSize)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "recreated": recreated})
+}
+
+// confirmed drives the two-person confirmation of destructive admin
+// operations: the first call is answered with a token, a second operator
+// replays the call with ?token= to run it. The identities come from
+// authentication, not from a client-supplied header, so a single operator
+// cannot issue the token under one name and replay it under another
+func confirmed(w http.ResponseWriter, r *http.Request, c controller.Controller, operation string) bool {
+	identity, err := c.Authenticate(r)
+	if err != nil {
+		wrapError(err, w, http.StatusUnauthorized)
+		return false
+	}
+	token, ok, err := c.RequireConfirmation(operation, identity.Username, r.URL.Query().Get("token"))
+	if err != nil {
+		wrapError(err, w, http.StatusForbidden)
+		return false
+	}
+	if ok != true {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status": "confirmation required",
+			"token":  token,
+		})
+		return false
+	}
+	return true
+}
+
+// GetBudgets displays the instance-hours consumption of every owner and
+// tenant against its monthly budget
+func GetBudgets(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	json.NewEncoder(w).Encode(c.Budgets())
+}
+
+// GetResourceKubeconfig returns a kubeconfig restricted to the namespace of
+// an instance. The ServiceAccount behind it is admin of the namespace, so
+// the credentials are only handed to the owners of the instance
+func GetResourceKubeconfig(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	if authorized(w, r, c, "get", resourceName) != true {
+		return
+	}
+	if ownsOrAdmin(w, r, c, resourceName, resourceID) != true {
+		return
+	}
+	kubeconfig, err := c.InstanceKubeconfig(resourceName, resourceID)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write([]byte(kubeconfig))
+}
+
+// HeartbeatResource records a keepalive for an instance using the heartbeat
+// lifecycle
+func HeartbeatResource(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	if authorized(w, r, c, "heartbeat", resourceName) != true {
+		return
+	}
+	err := c.Heartbeat(resourceName, resourceID)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+}
+
+// GetResourceStatus displays the exit status and artifacts location of a job
+// backed instance
+func GetResourceStatus(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	status, err := c.JobStatus(resourceName, resourceID)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(status)
+}
+
+// WaitForResource blocks until an instance reaches READY or FAILED, with an
+// optional ?timeout= bounded to ten minutes
+func WaitForResource(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+
+	timeout := 120 * time.Second
+	if r.URL.Query().Get("timeout") != "" {
+		parsed, err := time.ParseDuration(r.URL.Query().Get("timeout"))
+		if err != nil || parsed <= 0 {
+			wrapError(errors.New("invalid timeout, expected something like 120s"), w, http.StatusBadRequest)
+			return
+		}
+		timeout = parsed
+	}
+	if timeout > 10*time.Minute {
+		timeout = 10 * time.Minute
+	}
+
+	result, err := c.WaitForInstance(resourceName, resourceID, timeout)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	if result.State == "TIMEOUT" {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// ResurrectResource scales a retained instance back up with a fresh TTL
+func ResurrectResource(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	if authorized(w, r, c, "resurrect", resourceName) != true {
+		return
+	}
+	if ownsOrAdmin(w, r, c, resourceName, resourceID) != true {
+		return
+	}
+	err := c.ResurrectInstance(resourceName, resourceID)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+}
+
+// UndeleteResource cancels a pending deletion during the undo window
+func UndeleteResource(w http.ResponseWriter, r *http.Request,  c controller.Controller) {
+	resourceName := mux.Vars(r)["resource"]
+	resourceID := mux.Vars(r)["resourceId"]
+	if authorized(w, r, c, "undelete", resourceName) != true {
+		return
+	}
+	if ownsOrAdmin(w, r, c, resourceName, resourceID) != true {
+		return
+	}
+	err := c.UndeleteInstance(resourceName, resourceID)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+}
+
+// authorized verifies the access rules for the identity of the request,
+// answering 401 or 403 itself when the caller is not allowed
+func authorized(w http.ResponseWriter, r *http.Request, c controller.Controller, verb string, resourceName string) bool {
+	identity, err := c.Authenticate(r)
+	if err != nil {
+		wrapError(err, w, http.StatusUnauthorized)
+		return false
+	}
+	err = c.Authorize(identity.Username, verb, resourceName)
+	if err != nil {
+		wrapError(err, w, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// adminOnly verifies that the caller is allowed on the admin subtree: a
+// member of an OIDC admin group when OIDC is configured, otherwise an
+// identity granted the admin verb by the access rules
+func adminOnly(w http.ResponseWriter, r *http.Request, c controller.Controller, operation string) bool {
+	identity, err := c.Authenticate(r)
+	if err != nil {
+		wrapError(err, w, http.StatusUnauthorized)
+		return false
+	}
+	if c.OIDCEnabled() {
+		if c.IsAdmin(identity) != true {
+			wrapError(fmt.Errorf("%s is not an admin", identity.Username), w, http.StatusForbidden)
+			return false
+		}
+		return true
+	}
+	err = c.Authorize(identity.Username, "admin", operation)
+	if err != nil {
+		wrapError(err, w, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// ownsOrAdmin verifies that the authenticated identity owns the instance or
+// belongs to an admin group, only enforced when OIDC is configured
+func ownsOrAdmin(w http.ResponseWriter, r *http.Request, c controller.Controller, resourceName string, resourceID string) bool {
+	if c.OIDCEnabled() != true {
+		return true
+	}
+	identity, err := c.Authenticate(r)
+	if err != nil {
+		wrapError(err, w, http.StatusUnauthorized)
+		return false
+	}
+	if c.IsAdmin(identity) {
+		return true
+	}
+	owner, err := c.IsInstanceOwner(resourceName, resourceID, identity.Username)
+	if err != nil {
+		wrapError(err, w, http.StatusInternalServerError)
+		return false
+	}
+	if owner != true {
+		wrapError(fmt.Errorf("%s is not an owner of %s", identity.Username, resourceID), w, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
 func wrapError(err error, w http.ResponseWriter, status int) {
 	log.Println("API returned error: ", err)
 	w.WriteHeader(status)
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/geobeau/k8s-ephemeral-resources/controller"
+)
+
+// writeInventory renders the inventory as JSON, CSV or an aligned text
+// table, selected with ?output= or an Accept: text/csv header. Weekly
+// reports open the CSV straight in a spreadsheet without another layer of
+// scripting
+func writeInventory(w http.ResponseWriter, r *http.Request, entries []controller.InventoryEntry) {
+	output := r.URL.Query().Get("output")
+	if output == "" && strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		output = "csv"
+	}
+	switch output {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"namespace", "resource", "owner", "tenant", "expirationDate", "templateVersion"})
+		for _, entry := range entries {
+			writer.Write([]string{entry.Namespace, entry.Resource, entry.Owner, entry.Tenant, entry.ExpirationDate, entry.TemplateVersion})
+		}
+		writer.Flush()
+	case "table":
+		w.Header().Set("Content-Type", "text/plain")
+		writer := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(writer, "NAMESPACE\tRESOURCE\tOWNER\tTENANT\tEXPIRES")
+		for _, entry := range entries {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", entry.Namespace, entry.Resource, entry.Owner, entry.Tenant, entry.ExpirationDate)
+		}
+		writer.Flush()
+	default:
+		json.NewEncoder(w).Encode(entries)
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geobeau/k8s-ephemeral-resources/controller"
+)
+
+const slackUsage = "Usage: /ephemeral create <resource> | list <resource> | extend <resource> <instance> <duration> | delete <resource> <instance>"
+
+// SlackCommand answers the /ephemeral slash command, so demo environments
+// can be self served from Slack. The Slack user becomes the instance owner
+func SlackCommand(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	form, ok := slackForm(w, r, c)
+	if ok != true {
+		return
+	}
+
+	fields := strings.Fields(form.Get("text"))
+	owner := form.Get("user_name")
+	if len(fields) == 0 {
+		slackReply(w, slackUsage, nil)
+		return
+	}
+
+	switch fields[0] {
+	case "create":
+		if len(fields) != 2 {
+			slackReply(w, slackUsage, nil)
+			return
+		}
+		instance, err := c.CreateNewInstance(fields[1], controller.CreateOptions{Owner: owner})
+		if err != nil {
+			slackReply(w, "Creation failed: "+err.Error(), nil)
+			return
+		}
+		text := fmt.Sprintf("Instance `%s` is ready, it expires at %s", instance.Namespace, time.Unix(instance.ExpirationDate, 0).Format(time.RFC3339))
+		slackReply(w, text, slackInstanceButtons(fields[1], instance.Namespace))
+	case "list":
+		if len(fields) != 2 {
+			slackReply(w, slackUsage, nil)
+			return
+		}
+		instances, err := c.ListInstances(fields[1])
+		if err != nil {
+			slackReply(w, "Listing failed: "+err.Error(), nil)
+			return
+		}
+		lines := []string{fmt.Sprintf("%d instance(s) of %s:", len(instances), fields[1])}
+		for _, instance := range instances {
+			lines = append(lines, fmt.Sprintf("- `%s` owned by %s, expires %s", instance.Namespace, instance.Owner, instance.ExpirationDate))
+		}
+		slackReply(w, strings.Join(lines, "\n"), nil)
+	case "extend":
+		if len(fields) != 4 {
+			slackReply(w, slackUsage, nil)
+			return
+		}
+		pending, err := c.ExtendInstance(fields[1], fields[2], fields[3])
+		if err != nil {
+			slackReply(w, "Extension failed: "+err.Error(), nil)
+			return
+		}
+		if pending {
+			slackReply(w, "Extension recorded, it is waiting for an approval", nil)
+			return
+		}
+		slackReply(w, fmt.Sprintf("Extended `%s` by %s", fields[2], fields[3]), nil)
+	case "delete":
+		if len(fields) != 3 {
+			slackReply(w, slackUsage, nil)
+			return
+		}
+		deleteAt, err := c.MarkInstanceForDeletion(fields[1], fields[2])
+		if err != nil {
+			slackReply(w, "Deletion failed: "+err.Error(), nil)
+			return
+		}
+		slackReply(w, fmt.Sprintf("`%s` will be deleted at %s", fields[2], deleteAt.Format(time.RFC3339)), nil)
+	default:
+		slackReply(w, slackUsage, nil)
+	}
+}
+
+// SlackInteraction answers the buttons attached to the command replies
+func SlackInteraction(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	form, ok := slackForm(w, r, c)
+	if ok != true {
+		return
+	}
+
+	interaction := struct {
+		Actions []struct {
+			Value string `json:"value"`
+		} `json:"actions"`
+	}{}
+	err := json.Unmarshal([]byte(form.Get("payload")), &interaction)
+	if err != nil || len(interaction.Actions) == 0 {
+		wrapError(errors.New("invalid interaction payload"), w, http.StatusBadRequest)
+		return
+	}
+
+	parts := strings.Split(interaction.Actions[0].Value, "|")
+	if len(parts) != 3 {
+		wrapError(errors.New("invalid interaction value"), w, http.StatusBadRequest)
+		return
+	}
+	action, resource, instance := parts[0], parts[1], parts[2]
+
+	switch action {
+	case "extend":
+		_, err := c.ExtendInstance(resource, instance, "1h")
+		if err != nil {
+			slackReply(w, "Extension failed: "+err.Error(), nil)
+			return
+		}
+		slackReply(w, fmt.Sprintf("Extended `%s` by 1h", instance), nil)
+	case "delete":
+		_, err := c.MarkInstanceForDeletion(resource, instance)
+		if err != nil {
+			slackReply(w, "Deletion failed: "+err.Error(), nil)
+			return
+		}
+		slackReply(w, fmt.Sprintf("`%s` is marked for deletion", instance), nil)
+	default:
+		wrapError(errors.New("unknown interaction "+action), w, http.StatusBadRequest)
+	}
+}
+
+// slackSignatureWindow is how old a signed Slack request can be before it is
+// rejected as a replay, per the Slack documentation
+const slackSignatureWindow = 5 * time.Minute
+
+// slackForm verifies the Slack signature and parses the request form. The
+// endpoints are registered unconditionally, so without a signing secret the
+// requests are rejected instead of trusted
+func slackForm(w http.ResponseWriter, r *http.Request, c controller.Controller) (url.Values, bool) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		wrapError(err, w, http.StatusBadRequest)
+		return nil, false
+	}
+
+	secret := c.SlackSigningSecret()
+	if secret == "" {
+		wrapError(errors.New("no slack signing secret is configured"), w, http.StatusForbidden)
+		return nil, false
+	}
+
+	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+	epoch, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		wrapError(errors.New("invalid slack timestamp"), w, http.StatusForbidden)
+		return nil, false
+	}
+	age := time.Since(time.Unix(epoch, 0))
+	if age > slackSignatureWindow || age < -slackSignatureWindow {
+		wrapError(errors.New("slack timestamp outside the accepted window"), w, http.StatusForbidden)
+		return nil, false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Slack-Signature"))) != true {
+		wrapError(errors.New("invalid slack signature"), w, http.StatusForbidden)
+		return nil, false
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		wrapError(err, w, http.StatusBadRequest)
+		return nil, false
+	}
+	return form, true
+}
+
+// slackReply writes an in-channel Slack message, with optional blocks
+func slackReply(w http.ResponseWriter, text string, blocks []interface{}) {
+	reply := map[string]interface{}{"response_type": "in_channel", "text": text}
+	if blocks != nil {
+		blocks = append([]interface{}{map[string]interface{}{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": text},
+		}}, blocks...)
+		reply["blocks"] = blocks
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reply)
+}
+
+// slackInstanceButtons returns extend and delete buttons for an instance
+func slackInstanceButtons(resource string, instance string) []interface{} {
+	button := func(label string, action string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":  "button",
+			"text":  map[string]string{"type": "plain_text", "text": label},
+			"value": fmt.Sprintf("%s|%s|%s", action, resource, instance),
+		}
+	}
+	return []interface{}{map[string]interface{}{
+		"type":     "actions",
+		"elements": []interface{}{button("Extend 1h", "extend"), button("Delete", "delete")},
+	}}
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/geobeau/k8s-ephemeral-resources/controller"
+)
+
+type githubPREvent struct {
+	Action      string `json:"action"`
+	PullRequest struct {
+		Number int `json:"number"`
+		Head   struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"head"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+type gitlabMREvent struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		IID          int    `json:"iid"`
+		Action       string `json:"action"`
+		SourceBranch string `json:"source_branch"`
+		LastCommit   struct {
+			ID string `json:"id"`
+		} `json:"last_commit"`
+	} `json:"object_attributes"`
+}
+
+// VCSWebhook receives GitHub and GitLab pull request events and turns them
+// into preview environments: opened creates an instance, closed deletes it
+func VCSWebhook(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		wrapError(err, w, http.StatusBadRequest)
+		return
+	}
+
+	if r.Header.Get("X-GitHub-Event") != "" {
+		githubWebhook(w, r, c, body)
+		return
+	}
+	if r.Header.Get("X-Gitlab-Event") != "" {
+		gitlabWebhook(w, r, c, body)
+		return
+	}
+	wrapError(errors.New("unknown webhook origin"), w, http.StatusBadRequest)
+}
+
+func githubWebhook(w http.ResponseWriter, r *http.Request, c controller.Controller, body []byte) {
+	secret := c.WebhookSecret()
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Hub-Signature-256"))) != true {
+			wrapError(errors.New("invalid webhook signature"), w, http.StatusForbidden)
+			return
+		}
+	}
+	if r.Header.Get("X-GitHub-Event") != "pull_request" {
+		json.NewEncoder(w).Encode(map[string]string{"status": "ignored"})
+		return
+	}
+
+	event := githubPREvent{}
+	err := json.Unmarshal(body, &event)
+	if err != nil {
+		wrapError(err, w, http.StatusBadRequest)
+		return
+	}
+
+	switch event.Action {
+	case "opened", "reopened":
+		instance, err := c.OpenPreview("github", event.Repository.FullName, event.PullRequest.Number, event.PullRequest.Head.Ref, event.PullRequest.Head.SHA, event.PullRequest.User.Login)
+		if err != nil {
+			wrapError(err, w, http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(instance)
+	case "closed":
+		err := c.ClosePreview("github", event.Repository.FullName, event.PullRequest.Number)
+		if err != nil {
+			wrapError(err, w, http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+	default:
+		json.NewEncoder(w).Encode(map[string]string{"status": "ignored"})
+	}
+}
+
+func gitlabWebhook(w http.ResponseWriter, r *http.Request, c controller.Controller, body []byte) {
+	secret := c.WebhookSecret()
+	if secret != "" && hmac.Equal([]byte(r.Header.Get("X-Gitlab-Token")), []byte(secret)) != true {
+		wrapError(errors.New("invalid webhook token"), w, http.StatusForbidden)
+		return
+	}
+	if strings.HasPrefix(r.Header.Get("X-Gitlab-Event"), "Merge Request") != true {
+		json.NewEncoder(w).Encode(map[string]string{"status": "ignored"})
+		return
+	}
+
+	event := gitlabMREvent{}
+	err := json.Unmarshal(body, &event)
+	if err != nil {
+		wrapError(err, w, http.StatusBadRequest)
+		return
+	}
+
+	switch event.ObjectAttributes.Action {
+	case "open", "reopen":
+		instance, err := c.OpenPreview("gitlab", event.Project.PathWithNamespace, event.ObjectAttributes.IID, event.ObjectAttributes.SourceBranch, event.ObjectAttributes.LastCommit.ID, event.User.Username)
+		if err != nil {
+			wrapError(err, w, http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(instance)
+	case "close", "merge":
+		err := c.ClosePreview("gitlab", event.Project.PathWithNamespace, event.ObjectAttributes.IID)
+		if err != nil {
+			wrapError(err, w, http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+	default:
+		json.NewEncoder(w).Encode(map[string]string{"status": "ignored"})
+	}
+}
+
+// IdentityWebhook receives deactivation events from the identity provider
+// (SCIM-style push), handling the instances of the departed owners right away
+// instead of waiting for the next periodic sync. The provider must present
+// the configured shared secret: the endpoint schedules deletions, so without
+// a secret every push is rejected
+func IdentityWebhook(w http.ResponseWriter, r *http.Request, c controller.Controller) {
+	secret := c.IdentitySyncSecret()
+	if secret == "" {
+		wrapError(errors.New("no identity webhook secret is configured"), w, http.StatusForbidden)
+		return
+	}
+	if hmac.Equal([]byte(r.Header.Get("Authorization")), []byte("Bearer "+secret)) != true {
+		wrapError(errors.New("invalid identity webhook secret"), w, http.StatusForbidden)
+		return
+	}
+	event := struct {
+		Deactivated []string `json:"deactivated"`
+	}{}
+	err := json.NewDecoder(r.Body).Decode(&event)
+	if err != nil {
+		wrapError(err, w, http.StatusBadRequest)
+		return
+	}
+	if len(event.Deactivated) == 0 {
+		wrapError(errors.New("Invalid JSON. Please provide owners like: {\"deactivated\":[\"someowner\"]}"), w, http.StatusBadRequest)
+		return
+	}
+	affected := c.HandleDeactivatedOwners(event.Deactivated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"affected": affected})
+}
@@ -0,0 +1,75 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// clock abstracts time for the lifecycle logic. Production runs on the real
+// clock; staging can switch to a simulated clock (shifted and accelerated)
+// through the admin api, so expiries and grace periods can be exercised
+// without hours-long waits
+
+// Clock answers what time it is
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// simulatedClock starts from an offset point in time and advances at a
+// multiple of real time
+type simulatedClock struct {
+	activated time.Time
+	start     time.Time
+	factor    float64
+}
+
+func (s simulatedClock) Now() time.Time {
+	elapsed := time.Since(s.activated)
+	return s.start.Add(time.Duration(float64(elapsed) * s.factor))
+}
+
+var mutex sync.Mutex
+var active Clock = realClock{}
+
+// Now returns the current time of the active clock
+func Now() time.Time {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return active.Now()
+}
+
+// Since returns the time elapsed since @t on the active clock
+func Since(t time.Time) time.Duration {
+	return Now().Sub(t)
+}
+
+// Simulate replaces the active clock with one shifted by @offset and running
+// @factor times faster than real time
+func Simulate(offset time.Duration, factor float64) {
+	if factor <= 0 {
+		factor = 1
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	active = simulatedClock{activated: time.Now(), start: time.Now().Add(offset), factor: factor}
+}
+
+// Reset switches back to the real clock
+func Reset() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	active = realClock{}
+}
+
+// Set installs a custom clock
+func Set(c Clock) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	active = c
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"fmt"
+)
+
+// AccessRule grants verbs on resource types to identities or groups. Without
+// any rule in the configuration every identity is allowed everything, so
+// existing deployments keep working
+type AccessRule struct {
+	Identities []string `yaml:"identities"`
+	Resources  []string `yaml:"resources"`
+	Verbs      []string `yaml:"verbs"`
+}
+
+// Authorize verifies that an identity is allowed to perform a verb (create,
+// delete, extend, list) on a resource type
+func (c *Controller) Authorize(identity string, verb string, resourceName string) error {
+	if len(c.accessRules) == 0 {
+		return nil
+	}
+	for _, rule := range c.accessRules {
+		if rule.matches(identity, verb, resourceName) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not allowed to %s %s", identity, verb, resourceName)
+}
+
+// matches returns true when the rule grants the verb on the resource to the
+// identity
+func (r *AccessRule) matches(identity string, verb string, resourceName string) bool {
+	return matchesOne(r.Identities, identity) &&
+		matchesOne(r.Verbs, verb) &&
+		matchesOne(r.Resources, resourceName)
+}
+
+// matchesOne returns true when the list contains the value or the wildcard
+func matchesOne(allowed []string, value string) bool {
+	for _, entry := range allowed {
+		if entry == "*" || entry == value {
+			return true
+		}
+	}
+	return false
+}
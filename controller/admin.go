@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// frozenResources holds the resource types whose creation was disabled by an
+// admin, guarded by its own lock since the api and the loops share it
+type frozenResources struct {
+	sync.Mutex
+	reasons map[string]string
+}
+
+// FreezeResource disables the creation of new instances of a resource type,
+// used during maintenances or when a template is found to be broken
+func (c *Controller) FreezeResource(name string, reason string) error {
+	_, ok := c.Resources[name]
+	if ok != true {
+		return fmt.Errorf("Resource Not found")
+	}
+	if reason == "" {
+		reason = "frozen by an admin"
+	}
+	c.frozen.Lock()
+	defer c.frozen.Unlock()
+	log.Printf("Freezing resource %s: %s", name, reason)
+	c.frozen.reasons[name] = reason
+	return nil
+}
+
+// UnfreezeResource re-enables the creation of new instances of a resource type
+func (c *Controller) UnfreezeResource(name string) error {
+	_, ok := c.Resources[name]
+	if ok != true {
+		return fmt.Errorf("Resource Not found")
+	}
+	c.frozen.Lock()
+	defer c.frozen.Unlock()
+	log.Printf("Unfreezing resource %s", name)
+	delete(c.frozen.reasons, name)
+	return nil
+}
+
+// checkFrozen returns a FrozenError when the resource type was frozen by an
+// admin
+func (c *Controller) checkFrozen(name string) error {
+	c.frozen.Lock()
+	defer c.frozen.Unlock()
+	reason, ok := c.frozen.reasons[name]
+	if ok {
+		return &FrozenError{Reason: reason, NextAllowed: time.Time{}}
+	}
+	return nil
+}
+
+// DrainResource marks every instance of a resource type for deletion. The
+// owners keep the undo window to react before the instances are removed
+func (c *Controller) DrainResource(name string) ([]string, error) {
+	_, ok := c.Resources[name]
+	if ok != true {
+		return nil, fmt.Errorf("Resource Not found")
+	}
+
+	drained := []string{}
+	list, err := c.kubeClient.CoreV1().Namespaces().List(metav1.ListOptions{LabelSelector: "k8sEphemResourceName=" + name})
+	if err != nil {
+		return nil, err
+	}
+	for _, namespace := range list.Items {
+		_, err := c.MarkInstanceForDeletion(name, namespace.Name)
+		if err != nil {
+			log.Println("Error:", err)
+			continue
+		}
+		log.Printf("Draining %s", namespace.Name)
+		drained = append(drained, namespace.Name)
+	}
+	return drained, nil
+}
@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// AdmissionConfig configures the validating admission webhook protecting the
+// lifecycle labels of instance namespaces
+type AdmissionConfig struct {
+	// AllowedUsers can modify lifecycle labels and delete instance
+	// namespaces directly, typically the service account of the manager
+	AllowedUsers []string `yaml:"allowedUsers"`
+}
+
+// ReviewNamespaceAdmission decides whether a namespace change preserves the
+// lifecycle integrity of the instances. Unauthorized users cannot delete an
+// instance namespace or touch its ExpirationDate label directly: the api is
+// the only legitimate path, otherwise the cleanup loop loses track of the
+// instance
+func (c Controller) ReviewNamespaceAdmission(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: request.UID, Allowed: true}
+	if c.admission == nil || request.Kind.Kind != "Namespace" {
+		return response
+	}
+
+	var namespace, oldNamespace apiv1.Namespace
+	if len(request.Object.Raw) > 0 {
+		err := json.Unmarshal(request.Object.Raw, &namespace)
+		if err != nil {
+			log.Println("Error:", err)
+			return response
+		}
+	}
+	if len(request.OldObject.Raw) > 0 {
+		err := json.Unmarshal(request.OldObject.Raw, &oldNamespace)
+		if err != nil {
+			log.Println("Error:", err)
+			return response
+		}
+	}
+
+	subject := namespace
+	if request.Operation == admissionv1.Delete {
+		subject = oldNamespace
+	}
+	_, ok := subject.Labels["k8sEphemResourceName"]
+	if ok != true {
+		return response
+	}
+	if c.admissionUserAllowed(request.UserInfo.Username) {
+		return response
+	}
+
+	switch request.Operation {
+	case admissionv1.Delete:
+		return admissionDenied(request.UID, fmt.Sprintf("namespace %s belongs to an ephemeral instance: delete the instance through the api instead", subject.Name))
+	case admissionv1.Update:
+		if oldNamespace.Labels["k8sEphemResourceExpirationDate"] != namespace.Labels["k8sEphemResourceExpirationDate"] {
+			return admissionDenied(request.UID, fmt.Sprintf("the ExpirationDate label of %s is managed by the controller: extend the instance through the api instead", subject.Name))
+		}
+	}
+	return response
+}
+
+// admissionUserAllowed returns true when the user can bypass the webhook
+func (c Controller) admissionUserAllowed(username string) bool {
+	if c.admission == nil {
+		return false
+	}
+	for _, user := range c.admission.AllowedUsers {
+		if user == username {
+			return true
+		}
+	}
+	return false
+}
+
+// MutateWorkloadAdmission stamps the resource, instance and owner labels
+// onto an object created inside an instance namespace, so cost attribution
+// and log routing keep working even for objects users add manually
+func (c Controller) MutateWorkloadAdmission(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: request.UID, Allowed: true}
+	if request.Namespace == "" {
+		return response
+	}
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(request.Namespace, metav1.GetOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return response
+	}
+	resourceName, ok := namespace.Labels["k8sEphemResourceName"]
+	if ok != true {
+		return response
+	}
+
+	object := struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}{}
+	if len(request.Object.Raw) > 0 {
+		err = json.Unmarshal(request.Object.Raw, &object)
+		if err != nil {
+			log.Println("Error:", err)
+			return response
+		}
+	}
+
+	stamps := map[string]string{
+		"k8s-ephemeral-resources/resource": resourceName,
+		"k8s-ephemeral-resources/instance": namespace.Name,
+		"k8s-ephemeral-resources/owner":    namespace.Labels["k8sEphemResourceOwner"],
+	}
+	keys := make([]string, 0, len(stamps))
+	for key := range stamps {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	patch := []map[string]interface{}{}
+	if object.Metadata.Labels == nil {
+		patch = append(patch, map[string]interface{}{"op": "add", "path": "/metadata/labels", "value": map[string]string{}})
+	}
+	for _, key := range keys {
+		value := stamps[key]
+		if value == "" || object.Metadata.Labels[key] == value {
+			continue
+		}
+		patch = append(patch, map[string]interface{}{"op": "add", "path": "/metadata/labels/" + escapeJSONPointer(key), "value": value})
+	}
+	if len(patch) == 0 || (object.Metadata.Labels == nil && len(patch) == 1) {
+		return response
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		log.Println("Error:", err)
+		return response
+	}
+	patchType := admissionv1.PatchTypeJSONPatch
+	response.Patch = patchBytes
+	response.PatchType = &patchType
+	return response
+}
+
+// escapeJSONPointer escapes a label key for use in a JSON patch path
+func escapeJSONPointer(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+// admissionDenied returns a response rejecting the request with @reason
+func admissionDenied(uid types.UID, reason string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		UID:     uid,
+		Allowed: false,
+		Result:  &metav1.Status{Message: reason},
+	}
+}
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// createAlias creates an ExternalName service in the alias namespace of the
+// resource, pointing at the instance service. Application configurations can
+// use this stable hostname while instances churn
+func (c *Controller) createAlias(resource Resource, instance Instance, alias string) error {
+	labels := make(map[string]string)
+	labels["k8sEphemResourceInstance"] = instance.Namespace
+
+	service := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: alias, Labels: labels},
+		Spec: apiv1.ServiceSpec{
+			Type: apiv1.ServiceTypeExternalName,
+			ExternalName: fmt.Sprintf("%s.%s.svc.cluster.local", resource.Name, instance.Namespace),
+		},
+	}
+
+	log.Printf("Creating alias %s in %s for instance %s", alias, resource.AliasNamespace, instance.Namespace)
+	_, err := c.kubeClient.CoreV1().Services(resource.AliasNamespace).Create(service)
+	return err
+}
+
+// deleteAliases removes every alias service pointing at an instance
+func (c *Controller) deleteAliases(instanceNamespace string) {
+	aliasNamespaces := make(map[string]bool)
+	for _, resource := range c.Resources {
+		if resource.AliasNamespace != "" {
+			aliasNamespaces[resource.AliasNamespace] = true
+		}
+	}
+
+	listOptions := metav1.ListOptions{LabelSelector: "k8sEphemResourceInstance=" + instanceNamespace}
+	for aliasNamespace := range aliasNamespaces {
+		list, err := c.kubeClient.CoreV1().Services(aliasNamespace).List(listOptions)
+		if err != nil {
+			log.Println("Error:", err)
+			continue
+		}
+		for _, service := range list.Items {
+			log.Printf("Removing alias %s in %s", service.Name, aliasNamespace)
+			err = c.kubeClient.CoreV1().Services(aliasNamespace).Delete(service.Name, nil)
+			if err != nil {
+				log.Println("Error:", err)
+			}
+		}
+	}
+}
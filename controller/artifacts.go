@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/ghodss/yaml"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ArtifactConfig runs a collector job before an instance is deleted, copying
+// paths or logs into object storage so test results survive the environment
+type ArtifactConfig struct {
+	CollectorJobTemplate string `yaml:"collectorJobTemplate"`
+	Destination          string `yaml:"destination"`
+}
+
+// artifactContext is the template data of a collector job
+type artifactContext struct {
+	Instance
+	Destination string
+}
+
+// collectArtifacts drives the artifact collection of an instance being
+// removed. The collector job is created on the first call and the deletion
+// only proceeds once it finished. A failed collector does not hold the
+// deletion forever
+func (c *Controller) collectArtifacts(resource Resource, namespaceName string) (bool, error) {
+	job, err := c.kubeClient.BatchV1().Jobs(namespaceName).Get("artifact-collector", metav1.GetOptions{})
+	if err == nil {
+		_, done := jobFinishedAt(job)
+		if done && job.Status.Succeeded == 0 {
+			log.Printf("Artifact collection of %s failed, deleting anyway", namespaceName)
+		}
+		return done, nil
+	}
+
+	instance := Instance{Namespace: namespaceName}
+	destination, err := renderTemplate(namespaceName, resource.Artifacts.Destination, instance)
+	if err != nil {
+		return false, err
+	}
+	context := artifactContext{Instance: instance, Destination: destination}
+
+	log.Println("Parsing artifact collector configuration")
+	rendered, err := renderTemplate(namespaceName, resource.Artifacts.CollectorJobTemplate, context)
+	if err != nil {
+		return false, err
+	}
+	jsonBytes, err := yaml.YAMLToJSON([]byte(rendered))
+	if err != nil {
+		return false, err
+	}
+	kubeJob := batchv1.Job{}
+	err = json.Unmarshal(jsonBytes, &kubeJob)
+	if err != nil {
+		return false, err
+	}
+	kubeJob.Name = "artifact-collector"
+
+	log.Printf("Collecting artifacts of %s into %s", namespaceName, destination)
+	_, err = c.kubeClient.BatchV1().Jobs(namespaceName).Create(&kubeJob)
+	if err != nil {
+		return false, err
+	}
+	c.linkArtifacts(namespaceName, destination)
+	return false, nil
+}
+
+// linkArtifacts records the archive location on the namespace so the final
+// status of the instance points at it
+func (c *Controller) linkArtifacts(namespaceName string, destination string) {
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(namespaceName, metav1.GetOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	if namespace.Annotations == nil {
+		namespace.Annotations = map[string]string{}
+	}
+	namespace.Annotations["k8s-ephemeral-resources/artifacts-location"] = destination
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	if err != nil {
+		log.Println("Error:", err)
+	}
+}
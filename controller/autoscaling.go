@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"log"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AutoscalingConfig generates an HorizontalPodAutoscaler for the deployment
+// of an instance, so a staging environment scales down when idle
+type AutoscalingConfig struct {
+	MinReplicas int32 `yaml:"minReplicas"`
+	MaxReplicas int32 `yaml:"maxReplicas"`
+	TargetCPU   int32 `yaml:"targetCPU"`
+}
+
+// createAutoscaler creates the HPA targeting the deployment of an instance
+func (c *Controller) createAutoscaler(resource Resource, instance Instance, deploymentName string) error {
+	config := resource.Autoscaling
+	minReplicas := config.MinReplicas
+	if minReplicas == 0 {
+		minReplicas = 1
+	}
+	targetCPU := config.TargetCPU
+	if targetCPU == 0 {
+		targetCPU = 80
+	}
+
+	autoscaler := &autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: deploymentName},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{
+				Kind:       "Deployment",
+				Name:       deploymentName,
+				APIVersion: "apps/v1beta2",
+			},
+			MinReplicas:                    &minReplicas,
+			MaxReplicas:                    config.MaxReplicas,
+			TargetCPUUtilizationPercentage: &targetCPU,
+		},
+	}
+
+	log.Println("Creating kubernetes horizontal pod autoscaler")
+	_, err := c.kubeClient.AutoscalingV1().HorizontalPodAutoscalers(instance.Namespace).Create(autoscaler)
+	return err
+}
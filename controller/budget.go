@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BudgetConfig bounds the monthly instance-hours consumed per owner or
+// tenant. Subjects are owner or tenant names, consumption above the alert
+// threshold is reported and creations can optionally be blocked once the
+// budget is exhausted
+type BudgetConfig struct {
+	MonthlyInstanceHours map[string]float64 `yaml:"monthlyInstanceHours"`
+	AlertThreshold       float64            `yaml:"alertThreshold"`
+	BlockOnExceed        bool               `yaml:"blockOnExceed"`
+}
+
+// BudgetStatus is the consumption of a subject against its monthly budget
+type BudgetStatus struct {
+	Subject       string
+	ConsumedHours float64
+	BudgetHours   float64
+	Exceeded      bool
+}
+
+// budgetTracker accumulates instance-hours in memory, resetting when the
+// month changes. Consumption restarts from zero when the manager restarts
+type budgetTracker struct {
+	sync.Mutex
+	config  *BudgetConfig
+	month   time.Month
+	hours   map[string]float64
+	alerted map[string]bool
+}
+
+func newBudgetTracker(config *BudgetConfig) *budgetTracker {
+	return &budgetTracker{
+		config:  config,
+		month:   time.Now().Month(),
+		hours:   map[string]float64{},
+		alerted: map[string]bool{},
+	}
+}
+
+// accrue adds instance-hours to a subject, alerting once per month when the
+// consumption crosses the threshold
+func (b *budgetTracker) accrue(subject string, hours float64) {
+	if subject == "" {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.resetOnNewMonth()
+	b.hours[subject] += hours
+
+	budget, ok := b.config.MonthlyInstanceHours[subject]
+	if ok != true {
+		return
+	}
+	threshold := b.config.AlertThreshold
+	if threshold == 0 {
+		threshold = 0.8
+	}
+	if b.hours[subject] >= budget*threshold && b.alerted[subject] != true {
+		log.Printf("Alert: %s consumed %.1f of its %.1f monthly instance-hours", subject, b.hours[subject], budget)
+		b.alerted[subject] = true
+	}
+}
+
+// exceeded reports whether a subject consumed its whole monthly budget
+func (b *budgetTracker) exceeded(subject string) bool {
+	if subject == "" {
+		return false
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.resetOnNewMonth()
+	budget, ok := b.config.MonthlyInstanceHours[subject]
+	return ok && b.hours[subject] >= budget
+}
+
+// consumption returns the budget status of every known subject
+func (b *budgetTracker) consumption() []BudgetStatus {
+	b.Lock()
+	defer b.Unlock()
+	b.resetOnNewMonth()
+
+	subjects := map[string]bool{}
+	for subject := range b.hours {
+		subjects[subject] = true
+	}
+	for subject := range b.config.MonthlyInstanceHours {
+		subjects[subject] = true
+	}
+
+	names := []string{}
+	for subject := range subjects {
+		names = append(names, subject)
+	}
+	sort.Strings(names)
+
+	statuses := []BudgetStatus{}
+	for _, subject := range names {
+		budget := b.config.MonthlyInstanceHours[subject]
+		statuses = append(statuses, BudgetStatus{
+			Subject: subject,
+			ConsumedHours: b.hours[subject],
+			BudgetHours: budget,
+			Exceeded: budget > 0 && b.hours[subject] >= budget,
+		})
+	}
+	return statuses
+}
+
+func (b *budgetTracker) resetOnNewMonth() {
+	if time.Now().Month() != b.month {
+		b.month = time.Now().Month()
+		b.hours = map[string]float64{}
+		b.alerted = map[string]bool{}
+	}
+}
+
+// accrueBudgets charges every live instance to the budget of its owner and
+// tenant, called by the cleanup loop at each pass
+func (c *Controller) accrueBudgets(delay time.Duration) {
+	if c.budgets == nil {
+		return
+	}
+	list, err := c.kubeClient.CoreV1().Namespaces().List(metav1.ListOptions{LabelSelector: "k8sEphemResourceName"})
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	hours := delay.Hours()
+	for _, namespace := range list.Items {
+		c.budgets.accrue(namespace.Labels["k8sEphemResourceOwner"], hours)
+		c.budgets.accrue(namespace.Labels["k8sEphemResourceTenant"], hours)
+	}
+}
+
+// checkBudget refuses a creation when the owner or the tenant exhausted its
+// monthly budget and the config blocks on exceed
+func (c *Controller) checkBudget(options CreateOptions) error {
+	if c.budgets == nil || c.budgets.config.BlockOnExceed != true {
+		return nil
+	}
+	for _, subject := range []string{options.Owner, options.Tenant} {
+		if c.budgets.exceeded(subject) {
+			return fmt.Errorf("%s exhausted its monthly instance-hours budget", subject)
+		}
+	}
+	return nil
+}
+
+// Budgets returns the consumption of every owner and tenant against its
+// monthly budget
+func (c *Controller) Budgets() []BudgetStatus {
+	if c.budgets == nil {
+		return []BudgetStatus{}
+	}
+	return c.budgets.consumption()
+}
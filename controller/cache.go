@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// namespaceCache caches namespace listings for the GET endpoints with a
+// short TTL, so the apiserver load stays bounded when orchestration scripts
+// poll the api. Mutations bust the whole cache to keep reads fresh
+type namespaceCache struct {
+	sync.Mutex
+	ttl     time.Duration
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	fetched time.Time
+	list    *apiv1.NamespaceList
+}
+
+func newNamespaceCache() *namespaceCache {
+	return &namespaceCache{
+		ttl:     10 * time.Second,
+		entries: map[string]cacheEntry{},
+	}
+}
+
+// list returns the namespaces matching a label selector, served from the
+// cache when a fresh entry exists
+func (n *namespaceCache) list(kubeClient *kubernetes.Clientset, selector string) (*apiv1.NamespaceList, error) {
+	n.Lock()
+	entry, ok := n.entries[selector]
+	n.Unlock()
+	if ok && time.Since(entry.fetched) < n.ttl {
+		return entry.list, nil
+	}
+
+	list, err := kubeClient.CoreV1().Namespaces().List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	n.Lock()
+	n.entries[selector] = cacheEntry{fetched: time.Now(), list: list}
+	n.Unlock()
+	return list, nil
+}
+
+// bust drops every cached listing, called after any mutation
+func (n *namespaceCache) bust() {
+	n.Lock()
+	defer n.Unlock()
+	n.entries = map[string]cacheEntry{}
+}
+
+// cachedInstances lists the namespaces of a resource through the cache
+func (c *Controller) cachedInstances(selector string) (*apiv1.NamespaceList, error) {
+	return c.nsCache.list(c.kubeClient, selector)
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// CatalogEntry describes a requestable resource type, for portals and CLI
+// tab-completion
+type CatalogEntry struct {
+	Name            string   `json:"name"`
+	Description     string   `json:"description,omitempty"`
+	Backend         string   `json:"backend"`
+	DurationDefault string   `json:"durationDefault"`
+	Fixtures        []string `json:"fixtures,omitempty"`
+	Components      []string `json:"components,omitempty"`
+	Storage         bool     `json:"storage"`
+	PoolSize        int      `json:"poolSize,omitempty"`
+	ParamsSchema    map[string]interface{} `json:"paramsSchema,omitempty"`
+}
+
+var catalogGVR = schema.GroupVersionResource{Group: "ephemeralresources.geobeau.github.com", Version: "v1", Resource: "resourcecatalogs"}
+
+// Catalog returns the loaded resource catalog, sorted by name
+func (c *Controller) Catalog() []CatalogEntry {
+	catalog := []CatalogEntry{}
+	for _, resource := range c.Resources {
+		entry := CatalogEntry{
+			Name: resource.Name,
+			Description: resource.Description,
+			Backend: backendName(resource),
+			DurationDefault: time.Duration(resource.DurationDefault).String(),
+			Components: resource.Components,
+			Storage: resource.Storage != nil,
+			PoolSize: resource.PoolSize,
+			ParamsSchema: resource.ParamsSchema,
+		}
+		for fixture := range resource.Fixtures {
+			entry.Fixtures = append(entry.Fixtures, fixture)
+		}
+		sort.Strings(entry.Fixtures)
+		catalog = append(catalog, entry)
+	}
+	sort.Slice(catalog, func(i, j int) bool { return catalog[i].Name < catalog[j].Name })
+	return catalog
+}
+
+// backendName returns what kind of backend provisions a resource
+func backendName(resource Resource) string {
+	switch {
+	case resource.AliasNamespace != "":
+		return "alias"
+	case len(resource.Components) > 0:
+		return "composite"
+	case resource.Sandbox != nil:
+		return "sandbox"
+	case resource.Manifest != nil:
+		return "manifest"
+	case resource.Job != nil:
+		return "job"
+	case resource.Terraform != nil:
+		return "terraform"
+	case resource.Claim != nil:
+		return "claim"
+	}
+	return "deployment"
+}
+
+// PublishCatalog registers the catalog as a cluster-scoped custom resource,
+// best effort: clusters without the CRD only get the /catalog endpoint
+func (c *Controller) PublishCatalog() {
+	entries := []interface{}{}
+	for _, entry := range c.Catalog() {
+		item := map[string]interface{}{
+			"name": entry.Name,
+			"backend": entry.Backend,
+			"durationDefault": entry.DurationDefault,
+		}
+		if entry.Description != "" {
+			item["description"] = entry.Description
+		}
+		entries = append(entries, item)
+	}
+	catalog := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": catalogGVR.Group + "/" + catalogGVR.Version,
+		"kind":       "ResourceCatalog",
+		"metadata":   map[string]interface{}{"name": "k8s-ephemeral-resources"},
+		"spec":       map[string]interface{}{"resources": entries},
+	}}
+
+	client := c.dynamicClient.Resource(catalogGVR)
+	_, err := client.Create(catalog, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		existing, getErr := client.Get("k8s-ephemeral-resources", metav1.GetOptions{})
+		if getErr != nil {
+			log.Println("Error:", getErr)
+			return
+		}
+		catalog.SetResourceVersion(existing.GetResourceVersion())
+		_, err = client.Update(catalog, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		log.Println("Could not publish the resource catalog:", err)
+	}
+}
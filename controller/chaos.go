@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// InjectFault injects a fault into an instance for resilience testing.
+// Resources have to opt in with chaosEnabled
+func (c *Controller) InjectFault(name string, instanceID string, fault string) error {
+	resource, ok := c.Resources[name]
+	if ok != true {
+		return errors.New("Resource Not found")
+	}
+	if resource.ChaosEnabled != true {
+		return fmt.Errorf("resource %s does not allow fault injection", name)
+	}
+
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+
+	log.Printf("Injecting fault %s into %s", fault, instanceID)
+	switch fault {
+	case "kill-pod":
+		return c.killRandomPod(instanceID)
+	case "pause-deployment":
+		return c.setDeploymentsPaused(instanceID, true)
+	case "resume-deployment":
+		return c.setDeploymentsPaused(instanceID, false)
+	}
+	return fmt.Errorf("unknown fault: %s", fault)
+}
+
+// killRandomPod deletes one random pod of the instance
+func (c *Controller) killRandomPod(instanceID string) error {
+	pods, err := c.kubeClient.CoreV1().Pods(instanceID).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("no pod to kill in %s", instanceID)
+	}
+	pod := pods.Items[rand.Intn(len(pods.Items))]
+	log.Printf("Killing pod %s/%s", instanceID, pod.Name)
+	return c.kubeClient.CoreV1().Pods(instanceID).Delete(pod.Name, nil)
+}
+
+// setDeploymentsPaused pauses or resumes every deployment of the instance
+func (c *Controller) setDeploymentsPaused(instanceID string, paused bool) error {
+	deployments, err := c.kubeClient.AppsV1beta2().Deployments(instanceID).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	patch := []byte(fmt.Sprintf(`{"spec":{"paused":%t}}`, paused))
+	for _, deployment := range deployments.Items {
+		_, err = c.kubeClient.AppsV1beta2().Deployments(instanceID).Patch(deployment.Name, types.StrategicMergePatchType, patch)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
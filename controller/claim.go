@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ghodss/yaml"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ClaimBackend provisions a claim CR (Crossplane, Service Catalog...) instead
+// of raw workloads, so ephemeral cloud resources get the same TTL semantics
+type ClaimBackend struct {
+	Template string `yaml:"template"`
+	Group    string `yaml:"group"`
+	Version  string `yaml:"version"`
+	Resource string `yaml:"resource"`
+}
+
+// gvr returns the GroupVersionResource of the claim
+func (b *ClaimBackend) gvr() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: b.Group, Version: b.Version, Resource: b.Resource}
+}
+
+// provisionClaim creates the namespace of the instance and the claim CR in it
+func (c *Controller) provisionClaim(resource Resource, instance Instance, labels map[string]string) error {
+	identifier := instance.Namespace
+	namespace := &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: identifier, Labels: labels}}
+
+	log.Println("Creating namespace: ", identifier)
+
+	_, err := c.kubeClient.CoreV1().Namespaces().Create(namespace)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Parsing claim configuration")
+	rendered, err := instance.generateConfigFromTemplate(resource.Claim.Template)
+	if err != nil {
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
+	}
+	jsonBytes, err := yaml.YAMLToJSON([]byte(rendered))
+	if err != nil {
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
+	}
+	claim := &unstructured.Unstructured{}
+	err = claim.UnmarshalJSON(jsonBytes)
+	if err != nil {
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
+	}
+	claim.SetNamespace(identifier)
+
+	log.Println("Creating claim: ", claim.GetName())
+	_, err = c.dynamicClient.Resource(resource.Claim.gvr()).Namespace(identifier).Create(claim, metav1.CreateOptions{})
+	if err != nil {
+		log.Println("Error while create resource, removing namespace")
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
+	}
+	return nil
+}
+
+// ClaimReady derives the readiness of a claim backed instance from the
+// status conditions of its CR
+func (c *Controller) ClaimReady(name string, instanceID string) (bool, error) {
+	resource, ok := c.Resources[name]
+	if ok != true {
+		return false, fmt.Errorf("Resource Not found")
+	}
+	if resource.Claim == nil {
+		return false, fmt.Errorf("resource %s is not claim backed", name)
+	}
+
+	claims, err := c.dynamicClient.Resource(resource.Claim.gvr()).Namespace(instanceID).List(metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	for _, claim := range claims.Items {
+		conditions, found, err := unstructured.NestedSlice(claim.Object, "status", "conditions")
+		if err != nil || found != true {
+			return false, nil
+		}
+		ready := false
+		for _, item := range conditions {
+			condition, ok := item.(map[string]interface{})
+			if ok != true {
+				continue
+			}
+			if condition["type"] == "Ready" && condition["status"] == "True" {
+				ready = true
+			}
+		}
+		if ready != true {
+			return false, nil
+		}
+	}
+	return true, nil
+}
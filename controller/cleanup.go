@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CleanupConfig tunes how the cleanup loop removes expired instances. After
+// a weekend thousands of instances can expire at once: a bounded worker pool
+// drains them faster than a serial loop without flooding the apiserver
+type CleanupConfig struct {
+	// Parallelism bounds the concurrent deletions, 1 by default
+	Parallelism int `yaml:"parallelism"`
+	// DeletionsPerSecond rate-limits the deletions, 0 for unlimited
+	DeletionsPerSecond float64 `yaml:"deletionsPerSecond"`
+}
+
+// removal is one instance the cleanup loop decided to delete
+type removal struct {
+	resource  Resource
+	namespace string
+	reason    string
+	expiredAt time.Time
+}
+
+// processRemovals deletes the collected instances through the worker pool,
+// oldest expiry first so the longest overdue instances free their resources
+// before the rate limit slows the rest down
+func (c *Controller) processRemovals(removals []removal) {
+	sort.Slice(removals, func(i, j int) bool {
+		return removals[i].expiredAt.Before(removals[j].expiredAt)
+	})
+
+	parallelism := 1
+	var interval time.Duration
+	if c.cleanup != nil {
+		if c.cleanup.Parallelism > parallelism {
+			parallelism = c.cleanup.Parallelism
+		}
+		if c.cleanup.DeletionsPerSecond > 0 {
+			interval = time.Duration(float64(time.Second) / c.cleanup.DeletionsPerSecond)
+		}
+	}
+
+	jobs := make(chan removal)
+	waitGroup := sync.WaitGroup{}
+	for worker := 0; worker < parallelism; worker++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for job := range jobs {
+				log.Printf("Removing %s (%s)", job.namespace, job.reason)
+				c.recordPhase(job.resource.Name, job.namespace, "expiring")
+				c.removeInstance(job.resource, job.namespace)
+				reapedTotal.WithLabelValues(job.resource.Name, job.reason).Inc()
+				// The namespace is gone with its timeline, the metric is all
+				// that remains of the final transition
+				phaseTransitions.WithLabelValues(job.resource.Name, "deleted").Inc()
+			}
+		}()
+	}
+
+	var ticker *time.Ticker
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+	}
+	for _, job := range removals {
+		if ticker != nil {
+			<-ticker.C
+		}
+		jobs <- job
+	}
+	close(jobs)
+	waitGroup.Wait()
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// CommentBackConfig posts the coordinates of a preview instance back on the
+// pull request that created it
+type CommentBackConfig struct {
+	GithubToken               string `yaml:"githubToken"`
+	GithubAPI                 string `yaml:"githubApi"`
+	GitlabToken               string `yaml:"gitlabToken"`
+	GitlabAPI                 string `yaml:"gitlabApi"`
+	URLTemplate               string `yaml:"urlTemplate"`
+	CredentialsSecretTemplate string `yaml:"credentialsSecretTemplate"`
+}
+
+// previewComment renders the comment body posted on a pull request
+func (c *Controller) previewComment(instance Instance) (string, error) {
+	config := c.webhooks.CommentBack
+	environmentURL, err := renderTemplate(instance.Namespace, config.URLTemplate, instance)
+	if err != nil {
+		return "", err
+	}
+
+	body := fmt.Sprintf("Preview environment ready: %s\n", environmentURL)
+	if config.CredentialsSecretTemplate != "" {
+		secret, err := renderTemplate(instance.Namespace, config.CredentialsSecretTemplate, instance)
+		if err != nil {
+			return "", err
+		}
+		body += fmt.Sprintf("Credentials: secret `%s`\n", secret)
+	}
+	body += fmt.Sprintf("Expires at %s", time.Unix(instance.ExpirationDate, 0).Format(time.RFC3339))
+	return body, nil
+}
+
+// postPreviewComment comments the instance coordinates on the pull request
+// and returns the comment identifier, so the comment can be deleted with the
+// instance
+func (c *Controller) postPreviewComment(provider string, repository string, number int, instance Instance) (string, error) {
+	body, err := c.previewComment(instance)
+	if err != nil {
+		return "", err
+	}
+	if provider == "github" {
+		return c.githubComment(repository, number, body)
+	}
+	return c.gitlabComment(repository, number, body)
+}
+
+// deletePreviewComment removes the comment of a closed preview
+func (c *Controller) deletePreviewComment(provider string, repository string, commentID string) error {
+	config := c.webhooks.CommentBack
+	if provider == "github" {
+		path := fmt.Sprintf("%s/repos/%s/issues/comments/%s", githubAPI(config), repository, commentID)
+		return vcsRequest("DELETE", path, "token "+config.GithubToken, nil, nil)
+	}
+	path := fmt.Sprintf("%s/projects/%s/merge_requests/notes/%s", gitlabAPI(config), url.PathEscape(repository), commentID)
+	return vcsRequest("DELETE", path, config.GitlabToken, nil, nil)
+}
+
+func githubAPI(config *CommentBackConfig) string {
+	if config.GithubAPI != "" {
+		return config.GithubAPI
+	}
+	return "https://api.github.com"
+}
+
+func gitlabAPI(config *CommentBackConfig) string {
+	if config.GitlabAPI != "" {
+		return config.GitlabAPI
+	}
+	return "https://gitlab.com/api/v4"
+}
+
+func (c *Controller) githubComment(repository string, number int, body string) (string, error) {
+	config := c.webhooks.CommentBack
+	path := fmt.Sprintf("%s/repos/%s/issues/%d/comments", githubAPI(config), repository, number)
+	response := struct {
+		ID int64 `json:"id"`
+	}{}
+	err := vcsRequest("POST", path, "token "+config.GithubToken, map[string]string{"body": body}, &response)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(response.ID, 10), nil
+}
+
+func (c *Controller) gitlabComment(repository string, number int, body string) (string, error) {
+	config := c.webhooks.CommentBack
+	path := fmt.Sprintf("%s/projects/%s/merge_requests/%d/notes", gitlabAPI(config), url.PathEscape(repository), number)
+	response := struct {
+		ID int64 `json:"id"`
+	}{}
+	err := vcsRequest("POST", path, config.GitlabToken, map[string]string{"body": body}, &response)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(response.ID, 10), nil
+}
+
+// vcsRequest performs an authenticated call against a VCS API. GitHub reads
+// the Authorization header, GitLab its PRIVATE-TOKEN one, so both are set
+func vcsRequest(method string, path string, token string, payload interface{}, response interface{}) error {
+	var body *bytes.Buffer = bytes.NewBuffer(nil)
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewBuffer(data)
+	}
+	request, err := http.NewRequest(method, path, body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", token)
+	request.Header.Set("PRIVATE-TOKEN", token)
+
+	result, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer result.Body.Close()
+	if result.StatusCode >= 300 {
+		return fmt.Errorf("%s %s answered %s", method, path, result.Status)
+	}
+	if response != nil {
+		return json.NewDecoder(result.Body).Decode(response)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	appsv1beta2 "k8s.io/api/apps/v1beta2"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// composeFile is the subset of a docker-compose file used for the conversion
+type composeFile struct {
+	Services map[string]composeService `json:"services"`
+}
+
+type composeService struct {
+	Image       string         `json:"image"`
+	Command     composeCommand `json:"command"`
+	Ports       []string       `json:"ports"`
+	Environment composeEnv     `json:"environment"`
+}
+
+// composeEnv accepts both the map and the list forms of the compose
+// environment section
+type composeEnv map[string]string
+
+func (e *composeEnv) UnmarshalJSON(data []byte) error {
+	asMap := map[string]string{}
+	if err := json.Unmarshal(data, &asMap); err == nil {
+		*e = asMap
+		return nil
+	}
+	asList := []string{}
+	if err := json.Unmarshal(data, &asList); err != nil {
+		return err
+	}
+	asMap = map[string]string{}
+	for _, entry := range asList {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 {
+			asMap[parts[0]] = parts[1]
+		} else {
+			asMap[parts[0]] = ""
+		}
+	}
+	*e = asMap
+	return nil
+}
+
+// composeCommand accepts both the string and the list forms of the compose
+// command section
+type composeCommand []string
+
+func (c *composeCommand) UnmarshalJSON(data []byte) error {
+	asList := []string{}
+	if err := json.Unmarshal(data, &asList); err == nil {
+		*c = asList
+		return nil
+	}
+	asString := ""
+	if err := json.Unmarshal(data, &asString); err != nil {
+		return err
+	}
+	*c = strings.Fields(asString)
+	return nil
+}
+
+// draftResource mirrors the Resource yaml layout with only the fields the
+// conversion fills, so the generated draft stays readable
+type draftResource struct {
+	Name               string   `json:"resourceName"`
+	DurationDefault    string   `json:"durationDefault,omitempty"`
+	DeploymentTemplate string   `json:"deploymentTemplate,omitempty"`
+	ServiceTemplate    string   `json:"serviceTemplate,omitempty"`
+	Components         []string `json:"components,omitempty"`
+}
+
+type draftConfig struct {
+	Resources []draftResource `json:"resources"`
+}
+
+// ConvertCompose converts a docker-compose file into a draft resource
+// definition: one component resource per compose service and, when the stack
+// has several services, a composite resource tying them together. The result
+// is a starting point meant to be reviewed before being served
+func ConvertCompose(data []byte, name string) (string, error) {
+	compose := composeFile{}
+	jsonBytes, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return "", err
+	}
+	err = json.Unmarshal(jsonBytes, &compose)
+	if err != nil {
+		return "", err
+	}
+	if len(compose.Services) == 0 {
+		return "", fmt.Errorf("no services found in the compose file")
+	}
+
+	serviceNames := []string{}
+	for serviceName := range compose.Services {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Strings(serviceNames)
+
+	config := draftConfig{}
+	components := []string{}
+	for _, serviceName := range serviceNames {
+		service := compose.Services[serviceName]
+		resourceName := name + "-" + serviceName
+		deployment, err := composeDeployment(serviceName, service)
+		if err != nil {
+			return "", err
+		}
+		draft := draftResource{
+			Name: resourceName,
+			DurationDefault: "1h",
+			DeploymentTemplate: deployment,
+		}
+		if len(service.Ports) > 0 {
+			draft.ServiceTemplate, err = composeKubeService(serviceName, service)
+			if err != nil {
+				return "", err
+			}
+		}
+		config.Resources = append(config.Resources, draft)
+		components = append(components, resourceName)
+	}
+
+	if len(components) > 1 {
+		config.Resources = append(config.Resources, draftResource{
+			Name: name,
+			DurationDefault: "1h",
+			Components: components,
+		})
+	}
+
+	rendered, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(rendered), nil
+}
+
+// composeDeployment builds the draft deployment template of a compose service
+func composeDeployment(name string, service composeService) (string, error) {
+	replicas := int32(1)
+	container := apiv1.Container{
+		Name: name,
+		Image: service.Image,
+		Args: service.Command,
+	}
+
+	envNames := []string{}
+	for envName := range service.Environment {
+		envNames = append(envNames, envName)
+	}
+	sort.Strings(envNames)
+	for _, envName := range envNames {
+		container.Env = append(container.Env, apiv1.EnvVar{Name: envName, Value: service.Environment[envName]})
+	}
+
+	for _, port := range service.Ports {
+		containerPort, err := composeContainerPort(port)
+		if err != nil {
+			return "", err
+		}
+		container.Ports = append(container.Ports, apiv1.ContainerPort{ContainerPort: containerPort})
+	}
+
+	deployment := appsv1beta2.Deployment{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1beta2", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{"app": name},
+		},
+		Spec: appsv1beta2.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+				Spec: apiv1.PodSpec{Containers: []apiv1.Container{container}},
+			},
+		},
+	}
+
+	rendered, err := yaml.Marshal(deployment)
+	return string(rendered), err
+}
+
+// composeKubeService builds the draft service template of a compose service
+func composeKubeService(name string, service composeService) (string, error) {
+	ports := []apiv1.ServicePort{}
+	for _, port := range service.Ports {
+		containerPort, err := composeContainerPort(port)
+		if err != nil {
+			return "", err
+		}
+		ports = append(ports, apiv1.ServicePort{
+			Port: containerPort,
+			TargetPort: intstr.FromInt(int(containerPort)),
+		})
+	}
+
+	kubeService := apiv1.Service{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{"app": name},
+		},
+		Spec: apiv1.ServiceSpec{
+			Selector: map[string]string{"app": name},
+			Ports: ports,
+		},
+	}
+
+	rendered, err := yaml.Marshal(kubeService)
+	return string(rendered), err
+}
+
+// composeContainerPort extracts the container side of a compose port mapping
+// like 8080:80 or 127.0.0.1:8080:80
+func composeContainerPort(port string) (int32, error) {
+	parts := strings.Split(port, ":")
+	containerPort, err := strconv.Atoi(strings.SplitN(parts[len(parts)-1], "/", 2)[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid port mapping %s: %v", port, err)
+	}
+	return int32(containerPort), nil
+}
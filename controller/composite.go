@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/geobeau/k8s-ephemeral-resources/tracing"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// provisionComposite provisions every component of a composite resource into
+// a single namespace, so they share one TTL and are deleted together.
+// Components reach each other through their service names inside the namespace
+func (c *Controller) provisionComposite(resource Resource, instance Instance, labels map[string]string, span *tracing.Span) error {
+	identifier := instance.Namespace
+	namespace := &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: identifier, Labels: labels}}
+
+	log.Println("Creating namespace: ", identifier)
+
+	_, err := c.kubeClient.CoreV1().Namespaces().Create(namespace)
+	if err != nil {
+		return err
+	}
+	c.copyCAConfigMaps(identifier)
+
+	for _, name := range resource.Components {
+		component, ok := c.Resources[name]
+		if ok != true {
+			log.Println("Error while create resource, removing namespace")
+			c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+			return fmt.Errorf("unknown component %s in composite resource %s", name, resource.Name)
+		}
+
+		log.Printf("Creating component %s of %s", name, resource.Name)
+		err = c.createWorkload(component, instance, span)
+		if err != nil {
+			log.Println("Error while create resource, removing namespace")
+			c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"log"
+)
+
+// provisionSlots builds the semaphores limiting concurrent provisioning per
+// resource, for the resources declaring maxConcurrentProvisioning
+func provisionSlots(resources map[string]Resource) map[string]chan struct{} {
+	slots := map[string]chan struct{}{}
+	for name, resource := range resources {
+		if resource.MaxConcurrentProvisioning > 0 {
+			slots[name] = make(chan struct{}, resource.MaxConcurrentProvisioning)
+		}
+	}
+	return slots
+}
+
+// acquireProvisionSlot blocks until a provisioning slot of the resource is
+// free and returns the function releasing it. Requests waiting for a slot are
+// reported in the queue depth metric
+func (c *Controller) acquireProvisionSlot(resource Resource) func() {
+	slots, ok := c.provisionSlots[resource.Name]
+	if ok != true {
+		return func() {}
+	}
+
+	provisionQueueDepth.WithLabelValues(resource.Name).Inc()
+	log.Printf("Waiting for a provisioning slot of %s", resource.Name)
+	slots <- struct{}{}
+	provisionQueueDepth.WithLabelValues(resource.Name).Dec()
+
+	return func() {
+		<-slots
+	}
+}
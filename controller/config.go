@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// LoadConfig parses and validates a configuration file. Unknown fields are
+// rejected with their line number, so a typo like `durationDefalt` fails at
+// startup instead of silently becoming a zero TTL
+func LoadConfig(data []byte) (Config, error) {
+	config := Config{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(&config)
+	if err != nil {
+		return config, err
+	}
+
+	config.Resources, err = resolveInheritance(config.Resources)
+	if err != nil {
+		return config, err
+	}
+
+	err = validateConfig(config)
+	if err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// validateConfig checks the resource definitions for the mistakes a schema
+// cannot express: missing names, missing backends and broken templates
+func validateConfig(config Config) error {
+	seen := map[string]bool{}
+	for index, resource := range config.Resources {
+		if resource.Name == "" {
+			return fmt.Errorf("resources[%d]: resourceName is required", index)
+		}
+		if seen[resource.Name] {
+			return fmt.Errorf("resource %s is defined twice", resource.Name)
+		}
+		seen[resource.Name] = true
+
+		err := validateResource(resource)
+		if err != nil {
+			return fmt.Errorf("resource %s: %v", resource.Name, err)
+		}
+	}
+
+	for _, resource := range config.Resources {
+		for _, component := range resource.Components {
+			if seen[component] != true {
+				return fmt.Errorf("resource %s: unknown component %s", resource.Name, component)
+			}
+		}
+	}
+
+	if config.IdentitySync != nil {
+		switch config.IdentitySync.Action {
+		case "", "flag", "reassign", "delete":
+		default:
+			return fmt.Errorf("identitySync: unknown action %s", config.IdentitySync.Action)
+		}
+		if config.IdentitySync.Action == "reassign" && config.IdentitySync.ReassignTo == "" {
+			return fmt.Errorf("identitySync: reassign needs reassignTo")
+		}
+	}
+
+	if config.EventBus != nil {
+		switch config.EventBus.Kind {
+		case "nats", "kafka-rest":
+		default:
+			return fmt.Errorf("eventBus: unknown kind %s", config.EventBus.Kind)
+		}
+		if config.EventBus.URL == "" || config.EventBus.Subject == "" {
+			return fmt.Errorf("eventBus: url and subject are required")
+		}
+	}
+
+	if config.OIDC != nil && config.OIDC.Issuer == "" {
+		return fmt.Errorf("oidc: issuer is required")
+	}
+	return nil
+}
+
+// validateResource checks a single resource definition
+func validateResource(resource Resource) error {
+	backends := 0
+	if resource.Claim != nil {
+		backends++
+	}
+	if resource.Terraform != nil {
+		backends++
+	}
+	if resource.Sandbox != nil {
+		backends++
+	}
+	if resource.Job != nil {
+		backends++
+	}
+	if resource.Manifest != nil {
+		backends++
+	}
+	if backends > 1 {
+		return fmt.Errorf("only one of claim, terraform, sandbox, job and manifest can be set")
+	}
+	if resource.Claim != nil && (resource.Claim.Template == "" || resource.Claim.Resource == "") {
+		return fmt.Errorf("a claim needs a template and a resource")
+	}
+	if resource.Terraform != nil && (resource.Terraform.Image == "" || resource.Terraform.Source == "") {
+		return fmt.Errorf("a terraform backend needs an image and a source")
+	}
+	if resource.Job != nil && resource.Job.Template == "" {
+		return fmt.Errorf("a job backend needs a template")
+	}
+	if resource.NamespacePrefix != "" {
+		err := ValidateNamespacePrefix(resource.NamespacePrefix)
+		if err != nil {
+			return err
+		}
+	}
+	if resource.Manifest != nil && len(resource.Manifest.AllowedKinds) == 0 {
+		return fmt.Errorf("a manifest backend needs allowedKinds")
+	}
+	if resource.DNSCleanup != nil && resource.DNSCleanup.HostnameTemplate == "" {
+		return fmt.Errorf("dnsCleanup needs a hostnameTemplate")
+	}
+	if resource.Autoscaling != nil {
+		if resource.Autoscaling.MaxReplicas < 1 {
+			return fmt.Errorf("autoscaling needs maxReplicas")
+		}
+		if resource.Autoscaling.MinReplicas > resource.Autoscaling.MaxReplicas {
+			return fmt.Errorf("autoscaling minReplicas cannot exceed maxReplicas")
+		}
+	}
+
+	alias := resource.AliasNamespace != ""
+	composite := len(resource.Components) > 0
+	if backends == 0 && alias != true && composite != true && resource.DeploymentTemplate == "" {
+		return fmt.Errorf("a deploymentTemplate, a backend, components or an aliasNamespace is required")
+	}
+
+	if resource.DurationDefault < 0 {
+		return fmt.Errorf("durationDefault cannot be negative")
+	}
+	if resource.DurationDefault == 0 && alias != true {
+		return fmt.Errorf("durationDefault is required")
+	}
+
+	templates := map[string]string{
+		"deploymentTemplate": resource.DeploymentTemplate,
+		"serviceTemplate":    resource.ServiceTemplate,
+		"seedJobTemplate":    resource.SeedJobTemplate,
+	}
+	if resource.Claim != nil {
+		templates["claim.template"] = resource.Claim.Template
+	}
+	if resource.Job != nil {
+		templates["job.template"] = resource.Job.Template
+	}
+	for field, content := range templates {
+		if content == "" {
+			continue
+		}
+		_, err := template.New(field).Parse(content)
+		if err != nil {
+			return fmt.Errorf("%s does not parse: %v", field, err)
+		}
+	}
+	return nil
+}
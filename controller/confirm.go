@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// confirmationTTL is how long an issued confirmation token stays valid
+const confirmationTTL = 5 * time.Minute
+
+// pendingConfirmation is a destructive operation waiting for its second
+// approval
+type pendingConfirmation struct {
+	operation string
+	issuedBy  string
+	issuedAt  time.Time
+}
+
+// confirmations tracks the issued tokens of the two-person flow
+type confirmations struct {
+	sync.Mutex
+	tokens map[string]pendingConfirmation
+}
+
+// RequireConfirmation drives the two-person flow of destructive admin
+// operations. Without a token one is issued and the operation does not run,
+// with a valid token issued by someone else within five minutes it does.
+// Returns the issued token and whether the operation is confirmed
+func (c *Controller) RequireConfirmation(operation string, identity string, token string) (string, bool, error) {
+	if c.confirmations == nil {
+		return "", true, nil
+	}
+	c.confirmations.Lock()
+	defer c.confirmations.Unlock()
+
+	if token == "" {
+		issued := newConfirmationToken()
+		c.confirmations.tokens[issued] = pendingConfirmation{operation: operation, issuedBy: identity, issuedAt: time.Now()}
+		return issued, false, nil
+	}
+
+	pending, ok := c.confirmations.tokens[token]
+	if ok != true {
+		return "", false, fmt.Errorf("unknown confirmation token")
+	}
+	if time.Since(pending.issuedAt) > confirmationTTL {
+		delete(c.confirmations.tokens, token)
+		return "", false, fmt.Errorf("confirmation token expired, request a new one")
+	}
+	if pending.operation != operation {
+		return "", false, fmt.Errorf("confirmation token was issued for %s", pending.operation)
+	}
+	if pending.issuedBy == identity {
+		return "", false, fmt.Errorf("a different operator must confirm %s", operation)
+	}
+	delete(c.confirmations.tokens, token)
+	return "", true, nil
+}
+
+// newConfirmationToken returns a random token
+func newConfirmationToken() string {
+	buffer := make([]byte, 16)
+	rand.Read(buffer)
+	return hex.EncodeToString(buffer)
+}
@@ -11,8 +11,12 @@ import (
 	"time"
 	"strconv"
 
+	"github.com/geobeau/k8s-ephemeral-resources/clock"
+	"github.com/geobeau/k8s-ephemeral-resources/maintenance"
+	"github.com/geobeau/k8s-ephemeral-resources/tracing"
 	"github.com/lithammer/shortuuid"
 	"github.com/ghodss/yaml"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	apiv1 "k8s.io/api/core/v1"
 	appsv1 "k8s.io/api/apps/v1beta2"
@@ -22,88 +26,475 @@ import (
 // Config is an Ephemeral resources manager configuration
 type Config struct {
 	Resources []Resource `yaml:"resources"`
+	ExtendedResourceQuotas map[string]int64 `yaml:"extendedResourceQuotasPerOwner"`
+	Schedule *Schedule `yaml:"schedule"`
+	Tenants []Tenant `yaml:"tenants"`
+	Injection *Injection `yaml:"injection"`
+	TTLSafetyMargin time.Duration `yaml:"ttlSafetyMargin"`
+	Budgets *BudgetConfig `yaml:"budgets"`
+	Inventory *InventoryConfig `yaml:"inventory"`
+	AccessRules []AccessRule `yaml:"accessRules"`
+	Sharding *ShardingConfig `yaml:"sharding"`
+	Webhooks *WebhookConfig `yaml:"webhooks"`
+	Slack *SlackConfig `yaml:"slack"`
+	ImagePolicy *ImagePolicyConfig `yaml:"imagePolicy"`
+	Scanner *ScannerConfig `yaml:"scanner"`
+	MutationWebhook string `yaml:"mutationWebhook"`
+	Partials map[string]string `yaml:"partials"`
+	TTLTiers map[string]Duration `yaml:"ttlTiers"`
+	ProtectedNamespaces []string `yaml:"protectedNamespaces"`
+	RequireConfirmation bool `yaml:"requireConfirmation"`
+	IdentitySync *IdentitySyncConfig `yaml:"identitySync"`
+	Admission *AdmissionConfig `yaml:"admission"`
+	EventBus *EventBusConfig `yaml:"eventBus"`
+	ManagerURL string `yaml:"managerURL"`
+	OIDC *OIDCConfig `yaml:"oidc"`
+	Cleanup *CleanupConfig `yaml:"cleanup"`
+	AllowClockSimulation bool `yaml:"allowClockSimulation"`
 }
 
 // Controller controls a set of Resources
 type Controller struct {
 	Resources 	map[string]Resource
 	kubeClient	*kubernetes.Clientset
-	suffix 		string
+	dynamicClient	dynamic.Interface
+	prefix 		string
+	extendedResourceQuotas map[string]int64
+	schedule	*Schedule
+	tenants		map[string]Tenant
+	deleteGraceWindow time.Duration
+	remediateStuck bool
+	frozen		*frozenResources
+	provisionSlots map[string]chan struct{}
+	budgets		*budgetTracker
+	inventory	*InventoryConfig
+	accessRules	[]AccessRule
+	sharding	*shardState
+	nsCache		*namespaceCache
+	webhooks	*WebhookConfig
+	slack		*SlackConfig
+	imagePolicy	*ImagePolicyConfig
+	scanner		*ScannerConfig
+	mutationWebhook string
+	injection	*Injection
+	ttlSafetyMargin time.Duration
+	ttlTiers	map[string]Duration
+	protected	map[string]bool
+	confirmations	*confirmations
+	identitySync	*IdentitySyncConfig
+	admission	*AdmissionConfig
+	eventBus	*EventBusConfig
+	managerURL	string
+	oidc		*OIDCConfig
+	oidcKeys	*oidcKeyCache
+	cleanup		*CleanupConfig
+	allowClockSimulation bool
 }
 
 // NewControllerFromConfig return a new controller from configuration
-func NewControllerFromConfig(config Config, kubeClient *kubernetes.Clientset, suffix string) Controller {
+func NewControllerFromConfig(config Config, kubeClient *kubernetes.Clientset, dynamicClient dynamic.Interface, prefix string, deleteGraceWindow time.Duration, remediateStuck bool) Controller {
 	resources := make(map[string]Resource)
 	for _, resource := range config.Resources {
 		resources[resource.Name] = resource
 	}
+	tenants := make(map[string]Tenant)
+	for _, tenant := range config.Tenants {
+		tenants[tenant.Name] = tenant
+	}
+	var budgets *budgetTracker
+	if config.Budgets != nil {
+		budgets = newBudgetTracker(config.Budgets)
+	}
+	var sharding *shardState
+	if config.Sharding != nil {
+		sharding = &shardState{config: config.Sharding, memberID: newShardMemberID()}
+	}
+	templatePartials = config.Partials
+	var confirm *confirmations
+	if config.RequireConfirmation {
+		confirm = &confirmations{tokens: map[string]pendingConfirmation{}}
+	}
 	return Controller{
 		Resources: resources,
 		kubeClient: kubeClient,
-		suffix: suffix,
+		dynamicClient: dynamicClient,
+		prefix: prefix,
+		extendedResourceQuotas: config.ExtendedResourceQuotas,
+		schedule: config.Schedule,
+		tenants: tenants,
+		deleteGraceWindow: deleteGraceWindow,
+		remediateStuck: remediateStuck,
+		frozen: &frozenResources{reasons: map[string]string{}},
+		provisionSlots: provisionSlots(resources),
+		budgets: budgets,
+		inventory: config.Inventory,
+		accessRules: config.AccessRules,
+		sharding: sharding,
+		nsCache: newNamespaceCache(),
+		webhooks: config.Webhooks,
+		slack: config.Slack,
+		imagePolicy: config.ImagePolicy,
+		scanner: config.Scanner,
+		mutationWebhook: config.MutationWebhook,
+		injection: config.Injection,
+		ttlSafetyMargin: config.TTLSafetyMargin,
+		ttlTiers: config.TTLTiers,
+		protected: protectedNamespaces(config),
+		confirmations: confirm,
+		identitySync: config.IdentitySync,
+		admission: config.Admission,
+		eventBus: config.EventBus,
+		managerURL: config.ManagerURL,
+		oidc: config.OIDC,
+		oidcKeys: &oidcKeyCache{},
+		cleanup: config.Cleanup,
+		allowClockSimulation: config.AllowClockSimulation,
 	}
 }
 
+// ClockSimulationAllowed returns true when the configuration opted into the
+// simulated clock, which only staging environments should
+func (c *Controller) ClockSimulationAllowed() bool {
+	return c.allowClockSimulation
+}
+
+// CreateOptions are the caller supplied options of an instance creation
+type CreateOptions struct {
+	Owner        string
+	Tenant       string
+	Alias        string
+	Fixture      string
+	StorageSize  string
+	StorageClass string
+	TTLFrom           string
+	EstimatedDuration string
+	Lifecycle         string
+	Tier              string
+	Params            map[string]interface{}
+	Manifests         string
+}
+
 // CreateNewInstance creates a new instance inside Kubernetes
-func (c *Controller) CreateNewInstance(name string, owner string) (Instance, error) {
+func (c *Controller) CreateNewInstance(name string, options CreateOptions) (Instance, error) {
+	requestedAt := time.Now()
 	resource, ok := c.Resources[name]
 	if ok != true {
 		return Instance{}, errors.New("Resource Not found")
 	}
-	u := strings.ToLower(shortuuid.New())
-	identifier := fmt.Sprintf("%s%s-%s", c.suffix, resource.Name, u)
+	if options.Alias != "" && resource.AliasNamespace == "" {
+		return Instance{}, fmt.Errorf("resource %s does not support aliases", name)
+	}
+	if options.Fixture != "" {
+		_, ok := resource.Fixtures[options.Fixture]
+		if ok != true {
+			return Instance{}, fmt.Errorf("unknown fixture %s for resource %s", options.Fixture, name)
+		}
+	}
+	if options.Lifecycle != "" && options.Lifecycle != "ttl" && options.Lifecycle != "heartbeat" {
+		return Instance{}, fmt.Errorf("unknown lifecycle: %s", options.Lifecycle)
+	}
+
+	err := c.checkFrozen(name)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	tenant := c.tenants[options.Tenant]
+	err = c.checkTenant(tenant, resource)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	err = c.checkSchedule(resource)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	err = c.checkBudget(options)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	storageSize, storageClass, err := c.resolveStorage(resource, options)
+	if err != nil {
+		return Instance{}, err
+	}
+	err = c.checkExtendedResources(resource, options.Owner)
+	if err != nil {
+		return Instance{}, err
+	}
+	ttl, err := c.resolveTTL(resource, options)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	// Pooled instances are pre-provisioned with the default storage, so a
+	// request with explicit storage parameters cannot be served from the pool
+	if resource.PoolSize > 0 && options.StorageSize == "" && options.StorageClass == "" {
+		instance, claimed := c.claimPooledInstance(resource, options, ttl)
+		if claimed {
+			return instance, c.finalizeInstance(resource, instance, options)
+		}
+		log.Println("No pooled instance available for", name, "provisioning a new one")
+	}
 
 	instance := Instance{
-		Namespace: identifier,
-		Owner: owner,
-		ExpirationDate: time.Now().Add(resource.DurationDefault).Unix(),
+		Owner: options.Owner,
+		ExpirationDate: clock.Now().Add(ttl).Unix(),
+		StorageSize: storageSize,
+		StorageClass: storageClass,
+		Params: options.Params,
+		Manifests: options.Manifests,
 	}
 	labels := make(map[string]string)
 	labels["k8sEphemResourceName"] = name
 	labels["k8sEphemResourceExpirationDate"] = strconv.FormatInt(instance.ExpirationDate, 10)
-	labels["k8sEphemResourceOwner"] = owner
+	labels["k8sEphemResourceOwner"] = options.Owner
+	labels["k8sEphemResourceTemplateVersion"] = templateVersion(resource)
+	if options.Tenant != "" {
+		labels["k8sEphemResourceTenant"] = options.Tenant
+	}
+	if options.Lifecycle == "heartbeat" {
+		labels["k8sEphemResourceLifecycle"] = "heartbeat"
+		labels["k8sEphemResourceLastHeartbeat"] = strconv.FormatInt(clock.Now().Unix(), 10)
+	}
+
+	log.Printf("Creating instance of %s for owner %s (tenant: %s)", name, options.Owner, options.Tenant)
+
+	release := c.acquireProvisionSlot(resource)
+	defer release()
+
+	span := tracing.StartSpan("CreateNewInstance")
+	span.SetAttribute("resource", name)
+	span.SetAttribute("owner", options.Owner)
+	defer span.End()
+
+	// Each attempt gets a fresh namespace, recording the attempt count so a
+	// flaky first rollout stays visible on the instance
+	provisioningAt := time.Now()
+	attempts := warmUpAttempts(resource)
+	for attempt := 1; attempt <= attempts; attempt++ {
+		instance.Namespace = c.newIdentifier(resource, c.tenantPrefix(tenant))
+		labels["k8sEphemResourceProvisionAttempts"] = strconv.Itoa(attempt)
+
+		err = c.provisionWithRetry(resource, &instance, labels, span, tenant)
+		if err != nil {
+			return instance, err
+		}
+		readinessSpan := span.Child("readiness")
+		warm := c.warmedUp(resource, instance)
+		readinessSpan.End()
+		if warm {
+			break
+		}
+		log.Println("Removing failed attempt: ", instance.Namespace)
+		c.kubeClient.CoreV1().Namespaces().Delete(instance.Namespace, deleteOptions(resource))
+		if attempt == attempts {
+			return instance, fmt.Errorf("instance of %s FAILED: rollout did not complete after %d attempts", name, attempts)
+		}
+	}
+	c.nsCache.bust()
+	c.appendPhases(resource.Name, instance.Namespace, []PhaseEvent{
+		phaseEvent("requested", requestedAt),
+		phaseEvent("provisioning", provisioningAt),
+		phaseEvent("ready", time.Now()),
+	})
+	return instance, c.finalizeInstance(resource, instance, options)
+}
+
+// provision dispatches the provisioning of an instance to the backend of the
+// resource
+func (c *Controller) provision(resource Resource, instance Instance, labels map[string]string, span *tracing.Span) error {
+	if resource.Sandbox != nil {
+		child := span.Child("provision-sandbox")
+		defer child.End()
+		return c.provisionSandbox(resource, instance, labels)
+	}
+	if resource.Manifest != nil {
+		child := span.Child("provision-manifests")
+		defer child.End()
+		return c.provisionManifests(resource, instance, labels)
+	}
+	if resource.Job != nil {
+		child := span.Child("provision-job")
+		defer child.End()
+		return c.provisionJob(resource, instance, labels)
+	}
+	if resource.Terraform != nil {
+		child := span.Child("provision-terraform")
+		defer child.End()
+		return c.provisionTerraform(resource, instance, labels)
+	}
+	if resource.Claim != nil {
+		child := span.Child("provision-claim")
+		defer child.End()
+		return c.provisionClaim(resource, instance, labels)
+	}
+	if len(resource.Components) > 0 {
+		child := span.Child("provision-composite")
+		defer child.End()
+		return c.provisionComposite(resource, instance, labels, child)
+	}
+	child := span.Child("provision-instance")
+	defer child.End()
+	return c.provisionInstance(resource, instance, labels, child)
+}
+
+// warmedUp waits for the workloads of a deployment backed instance when the
+// resource declares a warm-up probe
+func (c *Controller) warmedUp(resource Resource, instance Instance) bool {
+	if resource.WarmUp == nil || resource.Terraform != nil || resource.Claim != nil || resource.Sandbox != nil || resource.Job != nil || resource.Manifest != nil {
+		return true
+	}
+	return c.waitForRollout(instance.Namespace, resource.WarmUp.Timeout)
+}
+
+// checkSchedule verifies that the global and per-resource schedules allow
+// creating an instance now
+func (c *Controller) checkSchedule(resource Resource) error {
+	now := time.Now()
+	if c.schedule != nil {
+		err := c.schedule.CheckCreationAllowed(now)
+		if err != nil {
+			return err
+		}
+	}
+	if resource.Schedule != nil {
+		return resource.Schedule.CheckCreationAllowed(now)
+	}
+	return nil
+}
+
+// finalizeInstance applies the creation options on a provisioned instance
+func (c *Controller) finalizeInstance(resource Resource, instance Instance, options CreateOptions) error {
+	c.announceExpiry(resource, instance.Namespace, time.Unix(instance.ExpirationDate, 0))
+	if resource.MetadataConfigMap {
+		c.writeMetadataConfigMap(resource, instance)
+	}
+	go c.publishLifecycleEvent("created", resource, instance.Namespace, instance.Owner)
+	if options.Alias != "" {
+		err := c.createAlias(resource, instance, options.Alias)
+		if err != nil {
+			return err
+		}
+	}
+	if options.Fixture != "" {
+		err := c.runSeedJob(resource, instance, options.Fixture)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newIdentifier returns a unique namespace name for an instance of a
+// resource. A per-resource prefix wins over the tenant and global ones, so
+// namespace-name-based tooling (quota defaults, network policies) can key
+// on the resource type
+func (c *Controller) newIdentifier(resource Resource, prefix string) string {
+	if resource.NamespacePrefix != "" {
+		prefix = resource.NamespacePrefix
+	}
+	u := strings.ToLower(shortuuid.New())
+	return fmt.Sprintf("%s%s-%s", prefix, resource.Name, u)
+}
+
+// provisionInstance creates the namespace, deployment and service of an instance
+func (c *Controller) provisionInstance(resource Resource, instance Instance, labels map[string]string, span *tracing.Span) error {
+	identifier := instance.Namespace
 	namespace := &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: identifier, Labels: labels}}
 
 	log.Println("Creating namespace: ", identifier)
 
+	namespaceSpan := span.Child("create-namespace")
 	_, err := c.kubeClient.CoreV1().Namespaces().Create(namespace)
+	namespaceSpan.End()
 	if err != nil {
-		return instance, err
+		return err
+	}
+	c.copyCAConfigMaps(identifier)
+
+	err = c.createWorkload(resource, instance, span)
+	if err != nil {
+		log.Println("Error while create resource, removing namespace")
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
 	}
 
+	return nil
+}
+
+// createWorkload creates the deployment and service of a resource inside the
+// namespace of the instance
+func (c *Controller) createWorkload(resource Resource, instance Instance, span *tracing.Span) error {
+	identifier := instance.Namespace
+
 	log.Println("Parsing deployment configuration")
 	deployment, err := instance.GenerateKubeDeploymentFromTemplate(resource.DeploymentTemplate)
 	if err != nil {
-		return instance, err
+		return err
+	}
+	c.injectIntoPodSpec(&deployment.Spec.Template.Spec)
+	addLogging(resource, instance, &deployment)
+	images, err := c.applyImagePolicy(&deployment.Spec.Template.Spec)
+	if err != nil {
+		return err
+	}
+	err = c.scanImages(resource, podImages(&deployment.Spec.Template.Spec))
+	if err != nil {
+		return err
+	}
+	err = c.applyMutationHooks("deployment", instance, &deployment)
+	if err != nil {
+		return err
 	}
 
 	log.Println("Creating kubernetes deployment")
+	deploymentSpan := span.Child("create-deployment")
 	_, err = c.kubeClient.AppsV1beta2().Deployments(identifier).Create(&deployment)
+	deploymentSpan.End()
 	if err != nil {
-		return instance, err
+		return err
+	}
+	c.recordImages(identifier, images)
+
+	if resource.Autoscaling != nil {
+		err = c.createAutoscaler(resource, instance, deployment.Name)
+		if err != nil {
+			return err
+		}
 	}
 
 	log.Println("Parsing service configuration")
 	service, err := instance.GenerateKubeServiceFromTemplate(resource.ServiceTemplate)
 	if err != nil {
-		return instance, err
+		return err
 	}
-
-	log.Println("Creating kubernetes service")
-	_, err = c.kubeClient.CoreV1().Services(identifier).Create(&service)
+	addMonitoring(resource, instance, &service)
+	err = c.applyMutationHooks("service", instance, &service)
 	if err != nil {
-		log.Println("Error while create resource, removing namespace")
-		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
-		return instance, err
+		return err
 	}
 
-	return instance, nil
+	log.Println("Creating kubernetes service")
+	serviceSpan := span.Child("create-service")
+	_, err = c.kubeClient.CoreV1().Services(identifier).Create(&service)
+	serviceSpan.End()
+	return err
 }
 
 // CleanupLoop wakes up every @delay to remove expired resources
 func (c *Controller) CleanupLoop(delay time.Duration) {
 	for {
+		if maintenance.Active() {
+			log.Println("Maintenance mode: skipping the verification loop")
+			time.Sleep(delay)
+			continue
+		}
 		log.Println("Running verification loop")
+		iterationStart := time.Now()
+		c.accrueBudgets(delay)
+		removals := []removal{}
 		for _, resource := range c.Resources {
 			listOptions := metav1.ListOptions{LabelSelector: "k8sEphemResourceName="+resource.Name}
 			list, err := c.kubeClient.CoreV1().Namespaces().List(listOptions)
@@ -111,7 +502,39 @@ func (c *Controller) CleanupLoop(delay time.Duration) {
 				log.Println("Error:", err)
 				continue
 			}
+			stuckCount := 0
 			for _, namespace := range list.Items {
+				if c.ownsInstance(namespace.Name) != true {
+					continue
+				}
+				if namespace.Status.Phase == apiv1.NamespaceTerminating {
+					if isStuckTerminating(&namespace) {
+						stuckCount++
+						c.reportStuckInstance(&namespace)
+					}
+					continue
+				}
+
+				deleteAtStr, ok := namespace.Labels["k8sEphemResourceDeleteAt"]
+				if ok {
+					deleteAtEpoch, err := strconv.ParseInt(deleteAtStr, 10, 64)
+					if err != nil {
+						log.Println("Error:", err)
+					} else if clock.Now().After(time.Unix(deleteAtEpoch, 0)) {
+						removals = append(removals, removal{resource, namespace.Name, "marked", time.Unix(deleteAtEpoch, 0)})
+						continue
+					}
+				}
+
+				if resource.Job != nil {
+					c.adjustJobExpiration(resource, &namespace)
+				}
+
+				if c.heartbeatExpired(resource, &namespace) {
+					removals = append(removals, removal{resource, namespace.Name, "heartbeat", clock.Now()})
+					continue
+				}
+
 				expirationDateStr, ok := namespace.Labels["k8sEphemResourceExpirationDate"]
 				if ok != true {
 					log.Printf("Ignoring: %s, expiration label not found", namespace.Name)
@@ -122,28 +545,119 @@ func (c *Controller) CleanupLoop(delay time.Duration) {
 					log.Println("Error:", err)
 					continue
 				}
+				// A suspended instance keeps its TTL clock frozen until it
+				// is resumed and its expiration pushed forward
+				if _, suspended := namespace.Labels["k8sEphemResourceSuspendedAt"]; suspended {
+					continue
+				}
+
 				expirationDate := time.Unix(expirationEpoch, 0)
-				if time.Now().After(expirationDate) {
-					log.Printf("%s is expired: now:%s / expire at:%s", namespace.Name, time.Now(), expirationDate)
-					log.Printf("Removing %s", namespace.Name)
-					err = c.kubeClient.CoreV1().Namespaces().Delete(namespace.Name, nil)
-					if err != nil {
-						log.Println("Error:", err)
+				if clock.Now().After(expirationDate) {
+					log.Printf("%s is expired: now:%s / expire at:%s", namespace.Name, clock.Now(), expirationDate)
+					if resource.Retention != nil && c.retainInsteadOfDelete(resource, &namespace) {
 						continue
 					}
+					removals = append(removals, removal{resource, namespace.Name, "expired", expirationDate})
 				}
 			}
+			stuckTerminating.WithLabelValues(resource.Name).Set(float64(stuckCount))
 		}
+		c.processRemovals(removals)
+		cleanupIterationDuration.WithLabelValues().Set(time.Since(iterationStart).Seconds())
+		cleanupLastSuccess.WithLabelValues().Set(float64(time.Now().Unix()))
 		time.Sleep(delay)
 	}
 }
 
+// removeInstance deletes the namespace of an instance along with its aliases.
+// Terraform backed instances are kept until their destroy job succeeded
+func (c *Controller) removeInstance(resource Resource, namespaceName string) {
+	if c.isProtected(namespaceName) {
+		log.Printf("Refusing to remove the protected namespace %s", namespaceName)
+		return
+	}
+	if resource.Artifacts != nil {
+		collected, err := c.collectArtifacts(resource, namespaceName)
+		if err != nil {
+			log.Println("Error:", err)
+			return
+		}
+		if collected != true {
+			log.Printf("Waiting for the artifact collection of %s", namespaceName)
+			return
+		}
+	}
+	if resource.Terraform != nil {
+		destroyed, err := c.destroyTerraform(resource, namespaceName)
+		if err != nil {
+			log.Println("Error:", err)
+			return
+		}
+		if destroyed != true {
+			log.Printf("Waiting for terraform destroy to complete in %s", namespaceName)
+			return
+		}
+	}
+	reportReleasedResources(resource, namespaceName)
+	c.deleteAliases(namespaceName)
+	err := c.applyPVCPolicy(resource, namespaceName)
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	err = c.kubeClient.CoreV1().Namespaces().Delete(namespaceName, deleteOptions(resource))
+	if err != nil {
+		log.Println("Error:", err)
+	}
+	if resource.DNSCleanup != nil {
+		go c.verifyDNSCleanup(resource, namespaceName)
+	}
+	go c.publishLifecycleEvent("deleted", resource, namespaceName, "")
+	c.nsCache.bust()
+}
+
 // Resource is a type of resource that can contains instances
 type Resource struct {
 	Name				string			`yaml:"resourceName"`
-	DurationDefault     time.Duration 	`yaml:"durationDefault"`
+	Description			string			`yaml:"description"`
+	Extends				string			`yaml:"extends"`
+	Abstract			bool			`yaml:"abstract"`
+	DurationDefault     Duration 		`yaml:"durationDefault"`
 	DeploymentTemplate	string			`yaml:"deploymentTemplate"`
 	ServiceTemplate		string			`yaml:"serviceTemplate"`
+	PoolSize			int				`yaml:"poolSize"`
+	Components			[]string		`yaml:"components"`
+	AliasNamespace		string			`yaml:"aliasNamespace"`
+	Fixtures			map[string]string	`yaml:"fixtures"`
+	SeedJobTemplate		string			`yaml:"seedJobTemplate"`
+	Storage				*StorageConfig	`yaml:"storage"`
+	ExtendedResources	map[string]int64	`yaml:"extendedResources"`
+	Schedule			*Schedule		`yaml:"schedule"`
+	HeartbeatInterval	time.Duration	`yaml:"heartbeatInterval"`
+	MaxMissedHeartbeats	int				`yaml:"maxMissedHeartbeats"`
+	Monitoring			*MonitoringConfig	`yaml:"monitoring"`
+	Logging				*LoggingConfig	`yaml:"logging"`
+	ChaosEnabled		bool			`yaml:"chaosEnabled"`
+	Resize				*ResizeConfig	`yaml:"resize"`
+	Claim				*ClaimBackend	`yaml:"claim"`
+	Terraform			*TerraformBackend	`yaml:"terraform"`
+	Deletion			*DeletionConfig	`yaml:"deletion"`
+	Extension			*ExtensionConfig	`yaml:"extension"`
+	MaxConcurrentProvisioning	int		`yaml:"maxConcurrentProvisioning"`
+	WarmUp				*WarmUpConfig	`yaml:"warmUp"`
+	Sandbox				*SandboxConfig	`yaml:"sandbox"`
+	Retention			*RetentionConfig	`yaml:"retention"`
+	Job				*JobBackend		`yaml:"job"`
+	Artifacts			*ArtifactConfig	`yaml:"artifacts"`
+	Scan				*ScanConfig		`yaml:"scan"`
+	ParamsSchema		map[string]interface{}	`yaml:"paramsSchema"`
+	ExpiryConfigMap		bool			`yaml:"expiryConfigMap"`
+	MetadataConfigMap	bool			`yaml:"metadataConfigMap"`
+	NamespacePrefix		string			`yaml:"namespacePrefix"`
+	Manifest			*ManifestBackend	`yaml:"manifest"`
+	DNSCleanup			*DNSCleanupConfig	`yaml:"dnsCleanup"`
+	Autoscaling			*AutoscalingConfig	`yaml:"autoscaling"`
+	Suspend				*SuspendConfig	`yaml:"suspend"`
 }
 
 // Instance is an instance of resource
@@ -151,6 +665,10 @@ type Instance struct {
 	Namespace		string
 	Owner			string
 	ExpirationDate	int64
+	StorageSize		string
+	StorageClass	string
+	Params			map[string]interface{}
+	Manifests		string	`json:"-"`
 }
 
 // ToStringMap returns a string map representation of the object
@@ -198,15 +716,32 @@ func (i *Instance) GenerateKubeServiceFromTemplate(templateString string) (apiv1
 
 // generateDeploymentFromTemplate Generate a deployment from template
 func (i *Instance) generateConfigFromTemplate(templateString string) (string, error) {
-	tmpl, err := template.New(i.Namespace).Parse(templateString)
+	return renderTemplate(i.Namespace, templateString, i)
+}
+
+// templatePartials are the shared snippets of the configuration, reusable
+// from every template
+var templatePartials map[string]string
+
+// renderTemplate renders a template with the given data. The configured
+// partials are available through {{template "partial-name" .}}
+func renderTemplate(name string, templateString string, data interface{}) (string, error) {
+	tmpl := template.New(name)
+	for partialName, partial := range templatePartials {
+		_, err := tmpl.New(partialName).Parse(partial)
+		if err != nil {
+			return "", fmt.Errorf("partial %s: %v", partialName, err)
+		}
+	}
+	tmpl, err := tmpl.Parse(templateString)
 	if err != nil {
-		return "", err 
+		return "", err
 	}
 
 	var resultBytes bytes.Buffer
-	err = tmpl.Execute(&resultBytes, i)
+	err = tmpl.Execute(&resultBytes, data)
 	if err != nil {
-		return "", err 
+		return "", err
 	}
 	return resultBytes.String(), nil
 }
\ No newline at end of file
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/geobeau/k8s-ephemeral-resources/clock"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MarkInstanceForDeletion marks an instance to be removed once the undo
+// window has passed, and returns the effective deletion time
+func (c *Controller) MarkInstanceForDeletion(name string, instanceID string) (time.Time, error) {
+	if c.isProtected(instanceID) {
+		return time.Time{}, fmt.Errorf("%s is a protected namespace", instanceID)
+	}
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return time.Time{}, fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+
+	deleteAt := clock.Now().Add(c.deleteGraceWindow)
+	namespace.Labels["k8sEphemResourceDeleteAt"] = strconv.FormatInt(deleteAt.Unix(), 10)
+
+	log.Printf("Marking %s for deletion at %s", instanceID, deleteAt)
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	c.nsCache.bust()
+	return deleteAt, err
+}
+
+// UndeleteInstance cancels a pending deletion during the undo window
+func (c *Controller) UndeleteInstance(name string, instanceID string) error {
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+	_, ok := namespace.Labels["k8sEphemResourceDeleteAt"]
+	if ok != true {
+		return fmt.Errorf("%s is not marked for deletion", instanceID)
+	}
+
+	delete(namespace.Labels, "k8sEphemResourceDeleteAt")
+
+	log.Printf("Cancelling deletion of %s", instanceID)
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	c.nsCache.bust()
+	return err
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DeletionConfig tunes how the instances of a resource are torn down
+type DeletionConfig struct {
+	Propagation string `yaml:"propagation"`
+	PVCPolicy   string `yaml:"pvcPolicy"`
+}
+
+// deleteOptions builds the namespace delete options from the deletion config
+// of a resource
+func deleteOptions(resource Resource) *metav1.DeleteOptions {
+	if resource.Deletion == nil || resource.Deletion.Propagation == "" {
+		return nil
+	}
+	propagation := metav1.DeletionPropagation(resource.Deletion.Propagation)
+	return &metav1.DeleteOptions{PropagationPolicy: &propagation}
+}
+
+// applyPVCPolicy applies the PVC reclamation policy of a resource before the
+// namespace is removed. Retained volumes have their PV switched to the Retain
+// reclaim policy, snapshotted ones get a VolumeSnapshot per PVC first
+func (c *Controller) applyPVCPolicy(resource Resource, namespaceName string) error {
+	if resource.Deletion == nil {
+		return nil
+	}
+	switch resource.Deletion.PVCPolicy {
+	case "", "delete":
+		return nil
+	case "retain":
+		return c.retainVolumes(namespaceName)
+	case "snapshot":
+		return c.snapshotVolumes(namespaceName)
+	}
+	return fmt.Errorf("unknown pvcPolicy %s for resource %s", resource.Deletion.PVCPolicy, resource.Name)
+}
+
+// retainVolumes switches the persistent volumes bound to the namespace to the
+// Retain reclaim policy so the data survives the namespace deletion
+func (c *Controller) retainVolumes(namespaceName string) error {
+	claims, err := c.kubeClient.CoreV1().PersistentVolumeClaims(namespaceName).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, claim := range claims.Items {
+		if claim.Spec.VolumeName == "" {
+			continue
+		}
+		log.Printf("Retaining volume %s of %s/%s", claim.Spec.VolumeName, namespaceName, claim.Name)
+		patch := []byte(`{"spec":{"persistentVolumeReclaimPolicy":"Retain"}}`)
+		_, err = c.kubeClient.CoreV1().PersistentVolumes().Patch(claim.Spec.VolumeName, types.StrategicMergePatchType, patch)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotVolumes creates a VolumeSnapshot per PVC of the namespace. The
+// snapshot contents outlive the namespace on CSI drivers
+func (c *Controller) snapshotVolumes(namespaceName string) error {
+	claims, err := c.kubeClient.CoreV1().PersistentVolumeClaims(namespaceName).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	snapshotGVR := schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1beta1", Resource: "volumesnapshots"}
+	for _, claim := range claims.Items {
+		log.Printf("Snapshotting %s/%s before deletion", namespaceName, claim.Name)
+		snapshot := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "snapshot.storage.k8s.io/v1beta1",
+			"kind":       "VolumeSnapshot",
+			"metadata": map[string]interface{}{
+				"name": fmt.Sprintf("%s-%d", claim.Name, time.Now().Unix()),
+			},
+			"spec": map[string]interface{}{
+				"source": map[string]interface{}{"persistentVolumeClaimName": claim.Name},
+			},
+		}}
+		_, err = c.dynamicClient.Resource(snapshotGVR).Namespace(namespaceName).Create(snapshot, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Diagnostics aggregates what is happening inside an instance namespace
+type Diagnostics struct {
+	Pods   []PodDiagnostic
+	Events []EventDiagnostic
+}
+
+// PodDiagnostic summarizes the state of one pod of an instance
+type PodDiagnostic struct {
+	Name       string
+	Phase      string
+	Containers []ContainerDiagnostic
+}
+
+// ContainerDiagnostic summarizes the state of one container of a pod
+type ContainerDiagnostic struct {
+	Name                  string
+	Ready                 bool
+	RestartCount          int32
+	LastTerminationReason string
+}
+
+// EventDiagnostic is a recent kubernetes event of the instance namespace
+type EventDiagnostic struct {
+	Type     string
+	Reason   string
+	Object   string
+	Message  string
+	Count    int32
+	LastSeen time.Time
+}
+
+// InstanceDiagnostics returns the recent events and pod statuses of an
+// instance, so users can diagnose an environment that never came up
+func (c *Controller) InstanceDiagnostics(name string, instanceID string) (Diagnostics, error) {
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return Diagnostics{}, err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return Diagnostics{}, fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+
+	diagnostics := Diagnostics{Pods: []PodDiagnostic{}, Events: []EventDiagnostic{}}
+
+	pods, err := c.kubeClient.CoreV1().Pods(instanceID).List(metav1.ListOptions{})
+	if err != nil {
+		return Diagnostics{}, err
+	}
+	for _, pod := range pods.Items {
+		podDiagnostic := PodDiagnostic{Name: pod.Name, Phase: string(pod.Status.Phase)}
+		for _, container := range pod.Status.ContainerStatuses {
+			containerDiagnostic := ContainerDiagnostic{
+				Name:         container.Name,
+				Ready:        container.Ready,
+				RestartCount: container.RestartCount,
+			}
+			if container.LastTerminationState.Terminated != nil {
+				containerDiagnostic.LastTerminationReason = container.LastTerminationState.Terminated.Reason
+			}
+			podDiagnostic.Containers = append(podDiagnostic.Containers, containerDiagnostic)
+		}
+		diagnostics.Pods = append(diagnostics.Pods, podDiagnostic)
+	}
+
+	events, err := c.kubeClient.CoreV1().Events(instanceID).List(metav1.ListOptions{})
+	if err != nil {
+		return Diagnostics{}, err
+	}
+	for _, event := range events.Items {
+		diagnostics.Events = append(diagnostics.Events, EventDiagnostic{
+			Type:     event.Type,
+			Reason:   event.Reason,
+			Object:   fmt.Sprintf("%s/%s", event.InvolvedObject.Kind, event.InvolvedObject.Name),
+			Message:  event.Message,
+			Count:    event.Count,
+			LastSeen: event.LastTimestamp.Time,
+		})
+	}
+
+	return diagnostics, nil
+}
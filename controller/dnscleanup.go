@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"log"
+	"net"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var certificateGVR = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+
+// DNSCleanupConfig asks for a post-delete verification that the DNS record
+// and the certificate of an instance really went away with its namespace,
+// so a misbehaving external-dns or cert-manager cannot silently leak entries
+type DNSCleanupConfig struct {
+	HostnameTemplate string   `yaml:"hostnameTemplate"`
+	TXTOwnerPrefix   string   `yaml:"txtOwnerPrefix"`
+	Attempts         int      `yaml:"attempts"`
+	Interval         Duration `yaml:"interval"`
+}
+
+// verifyDNSCleanup polls after the deletion of an instance until the DNS
+// record and the external-dns TXT registry entry are gone, retrying a few
+// times to leave the controllers time to reconcile. A record still present
+// after the last attempt is reported so an operator can clean it up
+func (c *Controller) verifyDNSCleanup(resource Resource, namespaceName string) {
+	hostname, err := renderTemplate(namespaceName, resource.DNSCleanup.HostnameTemplate, Instance{Namespace: namespaceName})
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+
+	attempts := resource.DNSCleanup.Attempts
+	if attempts == 0 {
+		attempts = 5
+	}
+	interval := time.Duration(resource.DNSCleanup.Interval)
+	if interval == 0 {
+		interval = 1 * time.Minute
+	}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if dnsRecordRemoved(resource.DNSCleanup, hostname) && c.certificateRemoved(hostname) {
+			log.Printf("DNS and certificate cleanup of %s verified for %s", hostname, namespaceName)
+			return
+		}
+		if attempt < attempts {
+			time.Sleep(interval)
+		}
+	}
+	log.Printf("Error: DNS record or certificate of %s from the deleted instance %s is still present after %d attempts", hostname, namespaceName, attempts)
+}
+
+// dnsRecordRemoved checks that neither the record of the instance nor its
+// external-dns TXT registry entry resolve anymore
+func dnsRecordRemoved(config *DNSCleanupConfig, hostname string) bool {
+	addresses, err := net.LookupHost(hostname)
+	if err == nil && len(addresses) > 0 {
+		return false
+	}
+	records, err := net.LookupTXT(config.TXTOwnerPrefix + hostname)
+	if err == nil && len(records) > 0 {
+		return false
+	}
+	return true
+}
+
+// certificateRemoved checks the cert-manager certificates for one still
+// covering the hostname of the deleted instance. Clusters without the CRD
+// pass the check
+func (c *Controller) certificateRemoved(hostname string) bool {
+	list, err := c.dynamicClient.Resource(certificateGVR).List(metav1.ListOptions{})
+	if err != nil {
+		return true
+	}
+	for _, item := range list.Items {
+		dnsNames, _, _ := unstructured.NestedStringSlice(item.Object, "spec", "dnsNames")
+		for _, name := range dnsNames {
+			if name == hostname {
+				return false
+			}
+		}
+	}
+	return true
+}
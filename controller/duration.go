@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration that accepts human strings in configuration
+// files, including day and week units ("2h", "45m", "3d", "1w")
+type Duration time.Duration
+
+// UnmarshalYAML parses a duration from an integer amount of nanoseconds or
+// from a human string
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	nanoseconds, err := strconv.ParseInt(value.Value, 10, 64)
+	if err == nil {
+		*d = Duration(nanoseconds)
+		return nil
+	}
+
+	parsed, err := parseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %v", value.Line, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// parseDuration extends time.ParseDuration with day and week units
+func parseDuration(raw string) (time.Duration, error) {
+	value := strings.TrimSpace(raw)
+	multiplier := time.Duration(1)
+	if strings.HasSuffix(value, "d") {
+		value = strings.TrimSuffix(value, "d") + "h"
+		multiplier = 24
+	} else if strings.HasSuffix(value, "w") {
+		value = strings.TrimSuffix(value, "w") + "h"
+		multiplier = 24 * 7
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, expected something like \"45m\", \"2h\" or \"3d\"", raw)
+	}
+	return parsed * multiplier, nil
+}
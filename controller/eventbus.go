@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EventBusConfig configures the optional lifecycle event publisher, so
+// downstream automation (billing, notifications, analytics) consumes a
+// durable stream instead of webhooks
+type EventBusConfig struct {
+	// Kind selects the bus: "nats" publishes over the NATS text protocol,
+	// "kafka-rest" posts to a Kafka REST proxy. Both are dependency-free,
+	// like the rest of the controller
+	Kind string `yaml:"kind"`
+	// URL is the nats://host:port address or the base url of the proxy
+	URL string `yaml:"url"`
+	// Subject is the NATS subject or the Kafka topic events go to
+	Subject string `yaml:"subject"`
+}
+
+// LifecycleEvent is what the bus carries for every instance transition
+type LifecycleEvent struct {
+	Event     string `json:"event"`
+	Resource  string `json:"resource"`
+	Instance  string `json:"instance"`
+	Owner     string `json:"owner,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+// publishLifecycleEvent publishes an instance transition on the configured
+// bus, best effort: a bus outage must never block the lifecycle itself
+func (c *Controller) publishLifecycleEvent(event string, resource Resource, instance string, owner string) {
+	if c.eventBus == nil {
+		return
+	}
+	payload, err := json.Marshal(LifecycleEvent{
+		Event:     event,
+		Resource:  resource.Name,
+		Instance:  instance,
+		Owner:     owner,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	switch c.eventBus.Kind {
+	case "nats":
+		err = publishNATS(c.eventBus.URL, c.eventBus.Subject, payload)
+	case "kafka-rest":
+		err = publishKafkaREST(c.eventBus.URL, c.eventBus.Subject, payload)
+	}
+	if err != nil {
+		log.Println("Error:", err)
+	}
+}
+
+// publishNATS publishes one message using the NATS text protocol directly,
+// small enough that a client library is not worth the dependency
+func publishNATS(url string, subject string, payload []byte) error {
+	address := strings.TrimPrefix(url, "nats://")
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	// The server greets with an INFO line
+	_, err = reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(conn, "CONNECT {\"verbose\":false}\r\nPUB %s %d\r\n%s\r\nPING\r\n", subject, len(payload), payload)
+	if err != nil {
+		return err
+	}
+	// Waiting for the PONG guarantees the server consumed the publish
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(line, "PONG") {
+			return nil
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			return fmt.Errorf("nats answered %s", strings.TrimSpace(line))
+		}
+	}
+}
+
+// publishKafkaREST posts one record to a topic through a Kafka REST proxy
+func publishKafkaREST(url string, topic string, payload []byte) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"records": []map[string]interface{}{
+			{"value": json.RawMessage(payload)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	response, err := http.Post(strings.TrimSuffix(url, "/")+"/topics/"+topic, "application/vnd.kafka.json.v2+json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("kafka rest proxy answered %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// expiryAnnotation is pre-announced on every instance namespace, so the
+// workloads inside can flush or checkpoint before the axe falls
+const expiryAnnotation = "ephemeral/expires-at"
+
+// announceExpiry writes the expiry annotation on the namespace of an
+// instance and refreshes the optional expiry ConfigMap
+func (c *Controller) announceExpiry(resource Resource, namespaceName string, expiresAt time.Time) {
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(namespaceName, metav1.GetOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	if namespace.Annotations == nil {
+		namespace.Annotations = map[string]string{}
+	}
+	namespace.Annotations[expiryAnnotation] = expiresAt.Format(time.RFC3339)
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	if err != nil {
+		log.Println("Error:", err)
+	}
+
+	if resource.ExpiryConfigMap {
+		owners := append([]string{namespace.Labels["k8sEphemResourceOwner"]}, splitOwners(namespace.Annotations[coOwnersAnnotation])...)
+		c.writeExpiryConfigMap(namespaceName, expiresAt, owners)
+	}
+}
+
+// writeExpiryConfigMap maintains a ConfigMap workloads can mount to learn
+// their expiry without talking to the kubernetes api
+func (c *Controller) writeExpiryConfigMap(namespaceName string, expiresAt time.Time, owners []string) {
+	configMap := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ephemeral-expiry", Namespace: namespaceName},
+		Data: map[string]string{
+			"expiresAt":      expiresAt.Format(time.RFC3339),
+			"expiresAtEpoch": strconv.FormatInt(expiresAt.Unix(), 10),
+			"owners":         strings.Join(owners, ","),
+		},
+	}
+	_, err := c.kubeClient.CoreV1().ConfigMaps(namespaceName).Create(configMap)
+	if errors.IsAlreadyExists(err) {
+		_, err = c.kubeClient.CoreV1().ConfigMaps(namespaceName).Update(configMap)
+	}
+	if err != nil {
+		log.Println("Error:", err)
+	}
+}
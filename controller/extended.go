@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkExtendedResources verifies that the cluster offers the extended
+// resources declared by a resource and that the owner stays within its quota
+func (c *Controller) checkExtendedResources(res Resource, owner string) error {
+	if len(res.ExtendedResources) == 0 {
+		return nil
+	}
+
+	nodes, err := c.kubeClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for name, needed := range res.ExtendedResources {
+		available := int64(0)
+		for _, node := range nodes.Items {
+			quantity, ok := node.Status.Allocatable[apiv1.ResourceName(name)]
+			if ok {
+				available += quantity.Value()
+			}
+		}
+		if available < needed {
+			return fmt.Errorf("cluster does not have %d %s available", needed, name)
+		}
+
+		quota, ok := c.extendedResourceQuotas[name]
+		if ok != true {
+			continue
+		}
+		used, err := c.extendedResourceUsage(owner, name)
+		if err != nil {
+			return err
+		}
+		if used+needed > quota {
+			return fmt.Errorf("owner %s would use %d %s, above the quota of %d", owner, used+needed, name, quota)
+		}
+	}
+
+	return nil
+}
+
+// extendedResourceUsage sums the extended resources held by the instances of
+// an owner, based on what their resource type declares
+func (c *Controller) extendedResourceUsage(owner string, resourceName string) (int64, error) {
+	listOptions := metav1.ListOptions{LabelSelector: "k8sEphemResourceOwner=" + owner}
+	list, err := c.kubeClient.CoreV1().Namespaces().List(listOptions)
+	if err != nil {
+		return 0, err
+	}
+
+	used := int64(0)
+	for _, namespace := range list.Items {
+		res, ok := c.Resources[namespace.Labels["k8sEphemResourceName"]]
+		if ok != true {
+			continue
+		}
+		used += res.ExtendedResources[resourceName]
+	}
+	return used, nil
+}
+
+// reportReleasedResources logs the extended resources freed by an expired instance
+func reportReleasedResources(res Resource, namespace string) {
+	for name, count := range res.ExtendedResources {
+		log.Printf("Releasing %d %s held by %s", count, name, namespace)
+	}
+}
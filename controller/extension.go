@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ExtensionConfig enables the approval mode for expiration extensions of a
+// resource. Extensions longer than the threshold are kept pending until an
+// admin approves them, preventing infinite extensions on expensive resources
+type ExtensionConfig struct {
+	ApprovalThreshold time.Duration `yaml:"approvalThreshold"`
+}
+
+// PendingExtension is an extension request waiting for an admin decision
+type PendingExtension struct {
+	Namespace string
+	Resource  string
+	Owner     string
+	Duration  string
+}
+
+// ExtendInstance pushes back the expiration date of an instance. When the
+// resource requires approvals and the extension is beyond the threshold, the
+// request is stored as pending instead and must be approved first. Returns
+// true when the extension was left pending
+func (c *Controller) ExtendInstance(name string, instanceID string, duration string) (bool, error) {
+	resource, ok := c.Resources[name]
+	if ok != true {
+		return false, fmt.Errorf("Resource Not found")
+	}
+	extension, err := time.ParseDuration(duration)
+	if err != nil {
+		return false, fmt.Errorf("invalid duration %s: %v", duration, err)
+	}
+
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return false, fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+
+	if resource.Extension != nil && extension > resource.Extension.ApprovalThreshold {
+		log.Printf("Extension of %s by %s requires an approval", instanceID, duration)
+		namespace.Labels["k8sEphemResourcePendingExtension"] = strconv.FormatInt(int64(extension.Seconds()), 10)
+		_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+		c.nsCache.bust()
+		return true, err
+	}
+
+	return false, c.applyExtension(namespace.Name, extension)
+}
+
+// applyExtension pushes back the expiration label of a namespace
+func (c *Controller) applyExtension(namespaceName string, extension time.Duration) error {
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(namespaceName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	expirationStr, ok := namespace.Labels["k8sEphemResourceExpirationDate"]
+	if ok != true {
+		return fmt.Errorf("%s has no expiration date", namespaceName)
+	}
+	expirationEpoch, err := strconv.ParseInt(expirationStr, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	newExpiration := time.Unix(expirationEpoch, 0).Add(extension)
+	log.Printf("Extending %s until %s", namespaceName, newExpiration.Format(time.RFC3339))
+	namespace.Labels["k8sEphemResourceExpirationDate"] = strconv.FormatInt(newExpiration.Unix(), 10)
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	if err != nil {
+		return err
+	}
+	resource, ok := c.Resources[namespace.Labels["k8sEphemResourceName"]]
+	if ok {
+		c.announceExpiry(resource, namespaceName, newExpiration)
+	}
+	c.recordPhase(namespace.Labels["k8sEphemResourceName"], namespaceName, "extended")
+	c.nsCache.bust()
+	return nil
+}
+
+// PendingExtensions lists the extension requests waiting for an admin
+func (c *Controller) PendingExtensions() ([]PendingExtension, error) {
+	pending := []PendingExtension{}
+	list, err := c.cachedInstances("k8sEphemResourcePendingExtension")
+	if err != nil {
+		return nil, err
+	}
+	for _, namespace := range list.Items {
+		seconds, err := strconv.ParseInt(namespace.Labels["k8sEphemResourcePendingExtension"], 10, 64)
+		if err != nil {
+			log.Println("Error:", err)
+			continue
+		}
+		pending = append(pending, PendingExtension{
+			Namespace: namespace.Name,
+			Resource: namespace.Labels["k8sEphemResourceName"],
+			Owner: namespace.Labels["k8sEphemResourceOwner"],
+			Duration: (time.Duration(seconds) * time.Second).String(),
+		})
+	}
+	return pending, nil
+}
+
+// ResolveExtension approves or denies a pending extension request
+func (c *Controller) ResolveExtension(instanceID string, approve bool) error {
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	secondsStr, ok := namespace.Labels["k8sEphemResourcePendingExtension"]
+	if ok != true {
+		return fmt.Errorf("%s has no pending extension", instanceID)
+	}
+	seconds, err := strconv.ParseInt(secondsStr, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	delete(namespace.Labels, "k8sEphemResourcePendingExtension")
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	if err != nil {
+		return err
+	}
+
+	if approve != true {
+		log.Printf("Extension of %s denied", instanceID)
+		return nil
+	}
+	log.Printf("Extension of %s approved", instanceID)
+	return c.applyExtension(instanceID, time.Duration(seconds)*time.Second)
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/ghodss/yaml"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// seedContext is the template data used to render a seed job. On top of the
+// instance fields it exposes the chosen fixture and its source
+type seedContext struct {
+	Instance
+	FixtureName   string
+	FixtureSource string
+}
+
+// runSeedJob creates the kubernetes job seeding an instance with a fixture
+func (c *Controller) runSeedJob(resource Resource, instance Instance, fixture string) error {
+	source, ok := resource.Fixtures[fixture]
+	if ok != true {
+		return fmt.Errorf("unknown fixture %s for resource %s", fixture, resource.Name)
+	}
+	if resource.SeedJobTemplate == "" {
+		return fmt.Errorf("resource %s has fixtures but no seedJobTemplate", resource.Name)
+	}
+
+	context := seedContext{
+		Instance: instance,
+		FixtureName: fixture,
+		FixtureSource: source,
+	}
+
+	log.Println("Parsing seed job configuration")
+	rendered, err := renderTemplate(instance.Namespace, resource.SeedJobTemplate, context)
+	if err != nil {
+		return err
+	}
+
+	jsonBytes, err := yaml.YAMLToJSON([]byte(rendered))
+	if err != nil {
+		return err
+	}
+
+	var kubeJob = batchv1.Job{}
+	err = json.Unmarshal(jsonBytes, &kubeJob)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Creating seed job with fixture %s in %s", fixture, instance.Namespace)
+	_, err = c.kubeClient.BatchV1().Jobs(instance.Namespace).Create(&kubeJob)
+	return err
+}
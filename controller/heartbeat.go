@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/geobeau/k8s-ephemeral-resources/clock"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Heartbeat records a keepalive for an instance using the heartbeat lifecycle
+func (c *Controller) Heartbeat(name string, instanceID string) error {
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+	if namespace.Labels["k8sEphemResourceLifecycle"] != "heartbeat" {
+		return fmt.Errorf("%s does not use the heartbeat lifecycle", instanceID)
+	}
+
+	namespace.Labels["k8sEphemResourceLastHeartbeat"] = strconv.FormatInt(clock.Now().Unix(), 10)
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	return err
+}
+
+// heartbeatExpired returns true when an instance using the heartbeat
+// lifecycle missed too many keepalives
+func (c *Controller) heartbeatExpired(resource Resource, namespace *apiv1.Namespace) bool {
+	if namespace.Labels["k8sEphemResourceLifecycle"] != "heartbeat" {
+		return false
+	}
+
+	lastHeartbeatStr, ok := namespace.Labels["k8sEphemResourceLastHeartbeat"]
+	if ok != true {
+		return false
+	}
+	lastHeartbeatEpoch, err := strconv.ParseInt(lastHeartbeatStr, 10, 64)
+	if err != nil {
+		log.Println("Error:", err)
+		return false
+	}
+
+	interval := resource.HeartbeatInterval
+	if interval == 0 {
+		interval = time.Minute
+	}
+	maxMissed := resource.MaxMissedHeartbeats
+	if maxMissed == 0 {
+		maxMissed = 3
+	}
+
+	deadline := time.Unix(lastHeartbeatEpoch, 0).Add(interval * time.Duration(maxMissed))
+	if clock.Now().After(deadline) {
+		log.Printf("%s missed more than %d heartbeats", namespace.Name, maxMissed)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// MutationHook receives rendered objects before they are applied and can
+// mutate them in place: inject sidecars, add annotations, rewrite storage
+// classes. Organizations register their last-mile tweaks here instead of
+// forking the controller
+type MutationHook interface {
+	// Name identifies the hook in errors
+	Name() string
+	// Mutate receives the kind of the object ("deployment", "service", ...)
+	// the instance it belongs to and a pointer to the object itself
+	Mutate(kind string, instance Instance, object interface{}) error
+}
+
+var mutationHooks []MutationHook
+
+// RegisterMutationHook registers a hook, called by custom builds from an
+// init function
+func RegisterMutationHook(hook MutationHook) {
+	mutationHooks = append(mutationHooks, hook)
+}
+
+// applyMutationHooks runs the registered hooks and the mutation webhook on a
+// rendered object before it is applied
+func (c *Controller) applyMutationHooks(kind string, instance Instance, object interface{}) error {
+	for _, hook := range mutationHooks {
+		err := hook.Mutate(kind, instance, object)
+		if err != nil {
+			return fmt.Errorf("mutation hook %s: %v", hook.Name(), err)
+		}
+	}
+	if c.mutationWebhook != "" {
+		err := c.callMutationWebhook(kind, instance, object)
+		if err != nil {
+			return fmt.Errorf("mutation webhook: %v", err)
+		}
+	}
+	return nil
+}
+
+// callMutationWebhook posts the rendered object to the configured webhook
+// and applies the object it answers with
+func (c *Controller) callMutationWebhook(kind string, instance Instance, object interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"kind":     kind,
+		"instance": instance,
+		"object":   object,
+	})
+	if err != nil {
+		return err
+	}
+
+	response, err := http.Post(c.mutationWebhook, "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", response.Status)
+	}
+
+	mutated := struct {
+		Object json.RawMessage `json:"object"`
+	}{}
+	err = json.NewDecoder(response.Body).Decode(&mutated)
+	if err != nil {
+		return err
+	}
+	if len(mutated.Object) == 0 {
+		return nil
+	}
+	return json.Unmarshal(mutated.Object, object)
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/geobeau/k8s-ephemeral-resources/clock"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IdentitySyncConfig closes the compliance gap of orphaned environments: the
+// directory is polled (or pushed through the identity webhook) and the
+// instances of deactivated owners are flagged, reassigned or scheduled for
+// deletion
+type IdentitySyncConfig struct {
+	URL        string   `yaml:"url"`
+	Interval   Duration `yaml:"interval"`
+	Action     string   `yaml:"action"` // flag, reassign or delete
+	ReassignTo string   `yaml:"reassignTo"`
+	Secret     string   `yaml:"secret"` // shared secret of the identity webhook
+}
+
+// IdentitySyncSecret returns the shared secret identity webhook pushes are
+// checked against, empty when none is configured
+func (c *Controller) IdentitySyncSecret() string {
+	if c.identitySync == nil {
+		return ""
+	}
+	return c.identitySync.Secret
+}
+
+// orphanedAnnotation marks the instances whose owner was deactivated
+const orphanedAnnotation = "k8s-ephemeral-resources/orphaned"
+
+// IdentitySyncLoop periodically fetches the active identities and handles the
+// instances whose owner disappeared from the directory
+func (c *Controller) IdentitySyncLoop() {
+	if c.identitySync == nil || c.identitySync.URL == "" {
+		return
+	}
+	interval := time.Duration(c.identitySync.Interval)
+	if interval == 0 {
+		interval = 1 * time.Hour
+	}
+	for {
+		active, err := c.fetchActiveIdentities()
+		if err != nil {
+			log.Println("Error:", err)
+			time.Sleep(interval)
+			continue
+		}
+
+		deactivated := []string{}
+		seen := map[string]bool{}
+		for _, resource := range c.Resources {
+			listOptions := metav1.ListOptions{LabelSelector: "k8sEphemResourceName=" + resource.Name}
+			list, err := c.kubeClient.CoreV1().Namespaces().List(listOptions)
+			if err != nil {
+				log.Println("Error:", err)
+				continue
+			}
+			for _, namespace := range list.Items {
+				owner := namespace.Labels["k8sEphemResourceOwner"]
+				if owner == "" || active[owner] || seen[owner] {
+					continue
+				}
+				seen[owner] = true
+				deactivated = append(deactivated, owner)
+			}
+		}
+		if len(deactivated) > 0 {
+			c.HandleDeactivatedOwners(deactivated)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// fetchActiveIdentities asks the directory for the identities still active,
+// expecting a JSON array of names
+func (c *Controller) fetchActiveIdentities() (map[string]bool, error) {
+	response, err := http.Get(c.identitySync.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return nil, fmt.Errorf("identity sync answered %s", response.Status)
+	}
+	identities := []string{}
+	err = json.NewDecoder(response.Body).Decode(&identities)
+	if err != nil {
+		return nil, err
+	}
+	active := map[string]bool{}
+	for _, identity := range identities {
+		active[identity] = true
+	}
+	return active, nil
+}
+
+// HandleDeactivatedOwners applies the configured action on every instance
+// belonging to one of the deactivated owners, returning the affected
+// instances
+func (c *Controller) HandleDeactivatedOwners(owners []string) []string {
+	affected := []string{}
+	for _, owner := range owners {
+		listOptions := metav1.ListOptions{LabelSelector: "k8sEphemResourceOwner=" + owner}
+		list, err := c.kubeClient.CoreV1().Namespaces().List(listOptions)
+		if err != nil {
+			log.Println("Error:", err)
+			continue
+		}
+		for _, namespace := range list.Items {
+			if c.ownsInstance(namespace.Name) != true {
+				continue
+			}
+			err := c.handleOrphan(&namespace, owner)
+			if err != nil {
+				log.Println("Error:", err)
+				continue
+			}
+			affected = append(affected, namespace.Name)
+		}
+	}
+	return affected
+}
+
+// handleOrphan flags, reassigns or schedules the deletion of one instance of
+// a deactivated owner
+func (c *Controller) handleOrphan(namespace *apiv1.Namespace, owner string) error {
+	name := namespace.Labels["k8sEphemResourceName"]
+	switch c.identitySync.Action {
+	case "reassign":
+		log.Printf("Reassigning %s from the deactivated owner %s to %s", namespace.Name, owner, c.identitySync.ReassignTo)
+		namespace.Labels["k8sEphemResourceOwner"] = c.identitySync.ReassignTo
+		_, err := c.kubeClient.CoreV1().Namespaces().Update(namespace)
+		c.nsCache.bust()
+		return err
+	case "delete":
+		log.Printf("Scheduling the deletion of %s, its owner %s was deactivated", namespace.Name, owner)
+		_, err := c.MarkInstanceForDeletion(name, namespace.Name)
+		return err
+	default:
+		log.Printf("Flagging %s as orphaned, its owner %s was deactivated", namespace.Name, owner)
+		if namespace.Annotations == nil {
+			namespace.Annotations = map[string]string{}
+		}
+		namespace.Annotations[orphanedAnnotation] = clock.Now().Format(time.RFC3339)
+		_, err := c.kubeClient.CoreV1().Namespaces().Update(namespace)
+		return err
+	}
+}
@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImagePolicyConfig validates and rewrites the images of instance workloads
+type ImagePolicyConfig struct {
+	AllowedRegistries []string `yaml:"allowedRegistries"`
+	ForbidLatest      bool     `yaml:"forbidLatest"`
+	PinDigests        bool     `yaml:"pinDigests"`
+}
+
+// applyImagePolicy enforces the image policy on a rendered pod spec and
+// returns the images it ended up with. With pinDigests the tags are resolved
+// to digests at create time, so the instance stays reproducible even if the
+// tag moves
+func (c *Controller) applyImagePolicy(spec *apiv1.PodSpec) ([]string, error) {
+	if c.imagePolicy == nil {
+		return nil, nil
+	}
+
+	images := []string{}
+	containers := []*apiv1.Container{}
+	for i := range spec.InitContainers {
+		containers = append(containers, &spec.InitContainers[i])
+	}
+	for i := range spec.Containers {
+		containers = append(containers, &spec.Containers[i])
+	}
+
+	for _, container := range containers {
+		err := c.validateImage(container.Image)
+		if err != nil {
+			return nil, err
+		}
+		if c.imagePolicy.PinDigests {
+			pinned, err := resolveDigest(container.Image)
+			if err != nil {
+				log.Printf("Could not pin %s: %v", container.Image, err)
+			} else {
+				container.Image = pinned
+			}
+		}
+		images = append(images, container.Image)
+	}
+	return images, nil
+}
+
+// validateImage checks an image against the allowed registries and the
+// :latest interdiction
+func (c *Controller) validateImage(image string) error {
+	policy := c.imagePolicy
+	if policy.ForbidLatest {
+		_, tag, _ := splitImage(image)
+		if tag == "latest" {
+			return fmt.Errorf("image %s violates the policy: tag your images, :latest is forbidden", image)
+		}
+	}
+	if len(policy.AllowedRegistries) == 0 {
+		return nil
+	}
+	registry := imageRegistry(image)
+	for _, allowed := range policy.AllowedRegistries {
+		if registry == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("image %s violates the policy: registry %s is not allowed", image, registry)
+}
+
+// imageRegistry returns the registry of an image, docker.io when implicit
+func imageRegistry(image string) string {
+	first := strings.Split(image, "/")[0]
+	if strings.ContainsAny(first, ".:") && strings.Contains(image, "/") {
+		return first
+	}
+	return "docker.io"
+}
+
+// splitImage separates the repository and the tag of an image. An image
+// pinned by digest has no tag to resolve
+func splitImage(image string) (string, string, bool) {
+	if strings.Contains(image, "@") {
+		return image, "", false
+	}
+	repository := image
+	tag := "latest"
+	lastColon := strings.LastIndex(image, ":")
+	if lastColon > strings.LastIndex(image, "/") {
+		repository = image[:lastColon]
+		tag = image[lastColon+1:]
+	}
+	return repository, tag, true
+}
+
+// resolveDigest resolves the tag of an image to its digest through the
+// registry v2 API
+func resolveDigest(image string) (string, error) {
+	repository, tag, tagged := splitImage(image)
+	if tagged != true {
+		return image, nil
+	}
+
+	registry := imageRegistry(image)
+	path := strings.TrimPrefix(repository, registry+"/")
+	host := registry
+	if registry == "docker.io" {
+		host = "registry-1.docker.io"
+		if strings.Contains(path, "/") != true {
+			path = "library/" + path
+		}
+	}
+
+	request, err := http.NewRequest("HEAD", fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, path, tag), nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json")
+	token, err := registryToken(host, path)
+	if err == nil && token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	digest := response.Header.Get("Docker-Content-Digest")
+	if response.StatusCode >= 300 || digest == "" {
+		return "", fmt.Errorf("registry %s answered %s for %s:%s", host, response.Status, path, tag)
+	}
+	return repository + "@" + digest, nil
+}
+
+// registryToken fetches an anonymous pull token for registries that require
+// one, like Docker Hub
+func registryToken(host string, path string) (string, error) {
+	if host != "registry-1.docker.io" {
+		return "", nil
+	}
+	response, err := http.Get(fmt.Sprintf("https://auth.docker.io/token?service=registry.docker.io&scope=repository:%s:pull", path))
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	token := struct {
+		Token string `json:"token"`
+	}{}
+	err = json.NewDecoder(response.Body).Decode(&token)
+	return token.Token, err
+}
+
+// recordImages stores the effective images on the namespace of an instance
+func (c *Controller) recordImages(namespaceName string, images []string) {
+	if len(images) == 0 {
+		return
+	}
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(namespaceName, metav1.GetOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	if namespace.Annotations == nil {
+		namespace.Annotations = map[string]string{}
+	}
+	namespace.Annotations["k8s-ephemeral-resources/images"] = strings.Join(images, ",")
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	if err != nil {
+		log.Println("Error:", err)
+	}
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// resolveInheritance expands `extends` between resource definitions: unset
+// fields of a child are taken from its base, so twenty nearly identical
+// resources share one definition. Abstract bases are dropped from the result
+func resolveInheritance(resources []Resource) ([]Resource, error) {
+	byName := map[string]Resource{}
+	for _, resource := range resources {
+		byName[resource.Name] = resource
+	}
+
+	resolved := []Resource{}
+	for _, resource := range resources {
+		expanded, err := expandResource(resource, byName, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		if expanded.Abstract {
+			continue
+		}
+		resolved = append(resolved, expanded)
+	}
+	return resolved, nil
+}
+
+// expandResource merges a resource with its (possibly chained) bases
+func expandResource(resource Resource, byName map[string]Resource, visiting map[string]bool) (Resource, error) {
+	if resource.Extends == "" {
+		return resource, nil
+	}
+	if visiting[resource.Name] {
+		return Resource{}, fmt.Errorf("resource %s: inheritance cycle through %s", resource.Name, resource.Extends)
+	}
+	visiting[resource.Name] = true
+
+	base, ok := byName[resource.Extends]
+	if ok != true {
+		return Resource{}, fmt.Errorf("resource %s extends unknown resource %s", resource.Name, resource.Extends)
+	}
+	base, err := expandResource(base, byName, visiting)
+	if err != nil {
+		return Resource{}, err
+	}
+	return mergeResource(resource, base), nil
+}
+
+// mergeResource fills the unset fields of a child with the ones of its base
+func mergeResource(child Resource, base Resource) Resource {
+	merged := child
+	target := reflect.ValueOf(&merged).Elem()
+	source := reflect.ValueOf(base)
+	for i := 0; i < target.NumField(); i++ {
+		if target.Field(i).IsZero() {
+			target.Field(i).Set(source.Field(i))
+		}
+	}
+	merged.Name = child.Name
+	merged.Extends = child.Extends
+	merged.Abstract = child.Abstract
+	return merged
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"log"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Injection is propagated into every instance workload, typically corporate
+// proxy settings, environment variables and CA bundles
+type Injection struct {
+	Env          map[string]string `yaml:"env"`
+	HTTPProxy    string            `yaml:"httpProxy"`
+	HTTPSProxy   string            `yaml:"httpsProxy"`
+	NoProxy      string            `yaml:"noProxy"`
+	CAConfigMaps []ConfigMapRef    `yaml:"caConfigMaps"`
+}
+
+// ConfigMapRef points at a ConfigMap to copy into every instance namespace
+type ConfigMapRef struct {
+	Namespace string `yaml:"namespace"`
+	Name      string `yaml:"name"`
+}
+
+// injectIntoPodSpec adds the configured environment variables and proxy
+// settings to every container of a rendered pod spec
+func (c *Controller) injectIntoPodSpec(spec *apiv1.PodSpec) {
+	if c.injection == nil {
+		return
+	}
+
+	env := []apiv1.EnvVar{}
+	for name, value := range c.injection.Env {
+		env = append(env, apiv1.EnvVar{Name: name, Value: value})
+	}
+	if c.injection.HTTPProxy != "" {
+		env = append(env, apiv1.EnvVar{Name: "HTTP_PROXY", Value: c.injection.HTTPProxy})
+	}
+	if c.injection.HTTPSProxy != "" {
+		env = append(env, apiv1.EnvVar{Name: "HTTPS_PROXY", Value: c.injection.HTTPSProxy})
+	}
+	if c.injection.NoProxy != "" {
+		env = append(env, apiv1.EnvVar{Name: "NO_PROXY", Value: c.injection.NoProxy})
+	}
+
+	for i := range spec.Containers {
+		spec.Containers[i].Env = append(spec.Containers[i].Env, env...)
+	}
+	for i := range spec.InitContainers {
+		spec.InitContainers[i].Env = append(spec.InitContainers[i].Env, env...)
+	}
+}
+
+// copyCAConfigMaps copies the configured CA bundles into an instance namespace
+func (c *Controller) copyCAConfigMaps(instanceNamespace string) {
+	if c.injection == nil {
+		return
+	}
+
+	for _, ref := range c.injection.CAConfigMaps {
+		configMap, err := c.kubeClient.CoreV1().ConfigMaps(ref.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			log.Println("Error:", err)
+			continue
+		}
+		copy := &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: configMap.Name},
+			Data: configMap.Data,
+			BinaryData: configMap.BinaryData,
+		}
+		log.Printf("Copying ConfigMap %s/%s into %s", ref.Namespace, ref.Name, instanceNamespace)
+		_, err = c.kubeClient.CoreV1().ConfigMaps(instanceNamespace).Create(copy)
+		if err != nil {
+			log.Println("Error:", err)
+		}
+	}
+}
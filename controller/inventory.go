@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InventoryConfig pushes the instance inventory to an external endpoint so
+// CMDB and capacity planning tools know about the ephemeral footprint without
+// scraping the kubernetes api
+type InventoryConfig struct {
+	URL      string        `yaml:"url"`
+	Interval time.Duration `yaml:"interval"`
+	Format   string        `yaml:"format"` // json (default) or ndjson
+}
+
+// InventoryEntry is one instance as pushed to the external endpoint
+type InventoryEntry struct {
+	Namespace       string `json:"namespace"`
+	Resource        string `json:"resource"`
+	Owner           string `json:"owner"`
+	Tenant          string `json:"tenant"`
+	ExpirationDate  string `json:"expirationDate,omitempty"`
+	TemplateVersion string `json:"templateVersion,omitempty"`
+}
+
+// InventoryLoop periodically pushes the inventory, skipping pushes when
+// nothing changed since the last one
+func (c *Controller) InventoryLoop() {
+	if c.inventory == nil || c.inventory.URL == "" {
+		return
+	}
+	interval := c.inventory.Interval
+	if interval == 0 {
+		interval = 5 * time.Minute
+	}
+
+	lastDigest := [sha256.Size]byte{}
+	for {
+		payload, err := c.inventoryPayload()
+		if err != nil {
+			log.Println("Error:", err)
+			time.Sleep(interval)
+			continue
+		}
+		digest := sha256.Sum256(payload)
+		if digest != lastDigest {
+			err = pushInventory(c.inventory.URL, payload)
+			if err != nil {
+				log.Println("Error:", err)
+			} else {
+				lastDigest = digest
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+// InventoryEntries collects every instance across all resources
+func (c *Controller) InventoryEntries() ([]InventoryEntry, error) {
+	list, err := c.kubeClient.CoreV1().Namespaces().List(metav1.ListOptions{LabelSelector: "k8sEphemResourceName"})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []InventoryEntry{}
+	for _, namespace := range list.Items {
+		entry := InventoryEntry{
+			Namespace: namespace.Name,
+			Resource: namespace.Labels["k8sEphemResourceName"],
+			Owner: namespace.Labels["k8sEphemResourceOwner"],
+			Tenant: namespace.Labels["k8sEphemResourceTenant"],
+			TemplateVersion: namespace.Labels["k8sEphemResourceTemplateVersion"],
+		}
+		expiration, err := expirationDate(&namespace)
+		if err == nil {
+			entry.ExpirationDate = expiration.Format(time.RFC3339)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// inventoryPayload collects every instance and serializes it in the
+// configured format
+func (c *Controller) inventoryPayload() ([]byte, error) {
+	entries, err := c.InventoryEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.inventory.Format == "ndjson" {
+		buffer := bytes.Buffer{}
+		encoder := json.NewEncoder(&buffer)
+		for _, entry := range entries {
+			err := encoder.Encode(entry)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return buffer.Bytes(), nil
+	}
+	return json.Marshal(entries)
+}
+
+// pushInventory posts the serialized inventory to the external endpoint
+func pushInventory(url string, payload []byte) error {
+	log.Println("Pushing inventory to: ", url)
+	response, err := http.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("inventory push to %s returned %s", url, response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/ghodss/yaml"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// JobBackend declares a resource whose main workload is a run-to-completion
+// job, like a benchmark. The instance auto-expires once the job finished plus
+// a log-retention window
+type JobBackend struct {
+	Template          string        `yaml:"template"`
+	LogRetention      time.Duration `yaml:"logRetention"`
+	ArtifactsLocation string        `yaml:"artifactsLocation"`
+}
+
+// JobInstanceStatus is the exit status of a job backed instance
+type JobInstanceStatus struct {
+	State             string
+	Active            int32
+	Succeeded         int32
+	Failed            int32
+	FinishedAt        string
+	ArtifactsLocation string
+}
+
+// provisionJob creates the namespace of the instance and its job in it
+func (c *Controller) provisionJob(resource Resource, instance Instance, labels map[string]string) error {
+	identifier := instance.Namespace
+	namespace := &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: identifier, Labels: labels}}
+
+	log.Println("Creating namespace: ", identifier)
+
+	_, err := c.kubeClient.CoreV1().Namespaces().Create(namespace)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Parsing job configuration")
+	rendered, err := instance.generateConfigFromTemplate(resource.Job.Template)
+	if err != nil {
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
+	}
+	jsonBytes, err := yaml.YAMLToJSON([]byte(rendered))
+	if err != nil {
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
+	}
+	kubeJob := batchv1.Job{}
+	err = json.Unmarshal(jsonBytes, &kubeJob)
+	if err != nil {
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
+	}
+
+	log.Println("Creating kubernetes job")
+	_, err = c.kubeClient.BatchV1().Jobs(identifier).Create(&kubeJob)
+	if err != nil {
+		log.Println("Error while create resource, removing namespace")
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
+	}
+	return nil
+}
+
+// adjustJobExpiration pulls the expiration of a job backed instance forward
+// once the job finished, so the namespace survives just the log-retention
+// window
+func (c *Controller) adjustJobExpiration(resource Resource, namespace *apiv1.Namespace) {
+	_, done := namespace.Labels["k8sEphemResourceJobFinished"]
+	if done {
+		return
+	}
+
+	finishedAt, finished := c.jobsFinishedAt(namespace.Name)
+	if finished != true {
+		return
+	}
+
+	newExpiration := finishedAt.Add(resource.Job.LogRetention)
+	expiration, err := expirationDate(namespace)
+	if err == nil && expiration.Before(newExpiration) {
+		return
+	}
+
+	log.Printf("Job of %s finished, expiring at %s", namespace.Name, newExpiration.Format(time.RFC3339))
+	namespace.Labels["k8sEphemResourceJobFinished"] = "true"
+	namespace.Labels["k8sEphemResourceExpirationDate"] = strconv.FormatInt(newExpiration.Unix(), 10)
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	c.nsCache.bust()
+}
+
+// jobsFinishedAt returns when the last job of the namespace finished, false
+// while any job is still running
+func (c *Controller) jobsFinishedAt(namespaceName string) (time.Time, bool) {
+	jobs, err := c.kubeClient.BatchV1().Jobs(namespaceName).List(metav1.ListOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return time.Time{}, false
+	}
+	if len(jobs.Items) == 0 {
+		return time.Time{}, false
+	}
+
+	finishedAt := time.Time{}
+	for _, job := range jobs.Items {
+		end, done := jobFinishedAt(&job)
+		if done != true {
+			return time.Time{}, false
+		}
+		if end.After(finishedAt) {
+			finishedAt = end
+		}
+	}
+	return finishedAt, true
+}
+
+// jobFinishedAt returns when a job completed or failed
+func jobFinishedAt(job *batchv1.Job) (time.Time, bool) {
+	if job.Status.CompletionTime != nil {
+		return job.Status.CompletionTime.Time, true
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == apiv1.ConditionTrue {
+			return condition.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// JobStatus exposes the exit status and the artifacts location of a job
+// backed instance
+func (c *Controller) JobStatus(name string, instanceID string) (JobInstanceStatus, error) {
+	resource, ok := c.Resources[name]
+	if ok != true {
+		return JobInstanceStatus{}, fmt.Errorf("Resource Not found")
+	}
+	if resource.Job == nil {
+		return JobInstanceStatus{}, fmt.Errorf("resource %s is not job backed", name)
+	}
+
+	jobs, err := c.kubeClient.BatchV1().Jobs(instanceID).List(metav1.ListOptions{})
+	if err != nil {
+		return JobInstanceStatus{}, err
+	}
+
+	status := JobInstanceStatus{State: "running"}
+	finished := len(jobs.Items) > 0
+	failed := false
+	finishedAt := time.Time{}
+	for _, job := range jobs.Items {
+		status.Active += job.Status.Active
+		status.Succeeded += job.Status.Succeeded
+		status.Failed += job.Status.Failed
+		end, done := jobFinishedAt(&job)
+		if done != true {
+			finished = false
+			continue
+		}
+		if job.Status.Succeeded == 0 {
+			failed = true
+		}
+		if end.After(finishedAt) {
+			finishedAt = end
+		}
+	}
+	if finished {
+		status.State = "succeeded"
+		if failed {
+			status.State = "failed"
+		}
+		status.FinishedAt = finishedAt.Format(time.RFC3339)
+	}
+
+	if resource.Job.ArtifactsLocation != "" {
+		location, err := renderTemplate(instanceID, resource.Job.ArtifactsLocation, Instance{Namespace: instanceID})
+		if err != nil {
+			return status, err
+		}
+		status.ArtifactsLocation = location
+	}
+	return status, nil
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const kubeconfigServiceAccount = "ephem-access"
+
+// InstanceKubeconfig generates a kubeconfig restricted to the namespace of an
+// instance. A service account bound to an admin role of the namespace is
+// created on the first call, so a CI job can kubectl into its own ephemeral
+// namespace and nothing else
+func (c *Controller) InstanceKubeconfig(name string, instanceID string) (string, error) {
+	_, ok := c.Resources[name]
+	if ok != true {
+		return "", fmt.Errorf("Resource Not found")
+	}
+
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return "", fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+
+	err = c.ensureNamespaceAccess(instanceID)
+	if err != nil {
+		return "", err
+	}
+
+	token, caData, err := c.serviceAccountToken(instanceID)
+	if err != nil {
+		return "", err
+	}
+
+	server := c.kubeClient.CoreV1().RESTClient().Get().URL()
+	return fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: %[1]s
+  cluster:
+    server: %[2]s://%[3]s
+    certificate-authority-data: %[4]s
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s
+    namespace: %[1]s
+    user: %[1]s
+current-context: %[1]s
+users:
+- name: %[1]s
+  user:
+    token: %[5]s
+`, instanceID, server.Scheme, server.Host, base64.StdEncoding.EncodeToString(caData), token), nil
+}
+
+// ensureNamespaceAccess creates the service account, role and rolebinding
+// giving full access to the namespace of an instance
+func (c *Controller) ensureNamespaceAccess(identifier string) error {
+	serviceAccount := &apiv1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: kubeconfigServiceAccount}}
+	_, err := c.kubeClient.CoreV1().ServiceAccounts(identifier).Create(serviceAccount)
+	if err != nil && errors.IsAlreadyExists(err) != true {
+		return err
+	}
+
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: kubeconfigServiceAccount},
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}},
+		},
+	}
+	_, err = c.kubeClient.RbacV1().Roles(identifier).Create(role)
+	if err != nil && errors.IsAlreadyExists(err) != true {
+		return err
+	}
+
+	roleBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: kubeconfigServiceAccount},
+		Subjects: []rbacv1.Subject{
+			{Kind: rbacv1.ServiceAccountKind, Name: kubeconfigServiceAccount, Namespace: identifier},
+		},
+		RoleRef: rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: kubeconfigServiceAccount},
+	}
+	_, err = c.kubeClient.RbacV1().RoleBindings(identifier).Create(roleBinding)
+	if err != nil && errors.IsAlreadyExists(err) != true {
+		return err
+	}
+	return nil
+}
+
+// serviceAccountToken waits for the token secret of the access service
+// account to be populated and returns the token and the cluster CA
+func (c *Controller) serviceAccountToken(identifier string) (string, []byte, error) {
+	for attempt := 0; attempt < 10; attempt++ {
+		serviceAccount, err := c.kubeClient.CoreV1().ServiceAccounts(identifier).Get(kubeconfigServiceAccount, metav1.GetOptions{})
+		if err != nil {
+			return "", nil, err
+		}
+		if len(serviceAccount.Secrets) == 0 {
+			log.Println("Waiting for the service account token of: ", identifier)
+			time.Sleep(time.Second)
+			continue
+		}
+		secret, err := c.kubeClient.CoreV1().Secrets(identifier).Get(serviceAccount.Secrets[0].Name, metav1.GetOptions{})
+		if err != nil {
+			return "", nil, err
+		}
+		return string(secret.Data["token"]), secret.Data["ca.crt"], nil
+	}
+	return "", nil, fmt.Errorf("timed out waiting for the service account token of %s", identifier)
+}
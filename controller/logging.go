@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/ghodss/yaml"
+	apiv1 "k8s.io/api/core/v1"
+	appsv1 "k8s.io/api/apps/v1beta2"
+)
+
+// LoggingConfig standardizes how the logs of a resource are shipped
+type LoggingConfig struct {
+	Team    string            `yaml:"team"`
+	Labels  map[string]string `yaml:"labels"`
+	Sidecar string            `yaml:"sidecar"`
+}
+
+// addLogging adds the standardized log routing labels to the pods of an
+// instance and injects the logging sidecar when one is configured
+func addLogging(resource Resource, instance Instance, deployment *appsv1.Deployment) {
+	if resource.Logging == nil {
+		return
+	}
+
+	if deployment.Spec.Template.Labels == nil {
+		deployment.Spec.Template.Labels = make(map[string]string)
+	}
+	deployment.Spec.Template.Labels["team"] = resource.Logging.Team
+	deployment.Spec.Template.Labels["instance"] = instance.Namespace
+	deployment.Spec.Template.Labels["owner"] = instance.Owner
+	for name, value := range resource.Logging.Labels {
+		deployment.Spec.Template.Labels[name] = value
+	}
+
+	if resource.Logging.Sidecar == "" {
+		return
+	}
+	jsonBytes, err := yaml.YAMLToJSON([]byte(resource.Logging.Sidecar))
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	var sidecar = apiv1.Container{}
+	err = json.Unmarshal(jsonBytes, &sidecar)
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers, sidecar)
+}
@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ManifestBackend declares a passthrough resource: the caller posts raw
+// manifests and the manager wraps them in an ephemeral namespace with a TTL,
+// restricted by the allowed kinds and resource caps
+type ManifestBackend struct {
+	AllowedKinds []string `yaml:"allowedKinds"`
+	CPU          string   `yaml:"cpu"`
+	Memory       string   `yaml:"memory"`
+}
+
+// provisionManifests creates the namespace of the instance and applies the
+// caller supplied manifests into it
+func (c *Controller) provisionManifests(resource Resource, instance Instance, labels map[string]string) error {
+	if strings.TrimSpace(instance.Manifests) == "" {
+		return fmt.Errorf("resource %s requires manifests in the create request", resource.Name)
+	}
+
+	objects, err := c.parseManifests(resource, instance.Manifests)
+	if err != nil {
+		return err
+	}
+
+	identifier := instance.Namespace
+	namespace := &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: identifier, Labels: labels}}
+
+	log.Println("Creating namespace: ", identifier)
+	_, err = c.kubeClient.CoreV1().Namespaces().Create(namespace)
+	if err != nil {
+		return err
+	}
+
+	quota, err := manifestQuota(resource.Manifest)
+	if err == nil && quota != nil {
+		_, err = c.kubeClient.CoreV1().ResourceQuotas(identifier).Create(quota)
+	}
+	if err == nil {
+		err = c.applyManifests(objects, identifier)
+	}
+	if err != nil {
+		log.Println("Error while create resource, removing namespace")
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
+	}
+	return nil
+}
+
+// parseManifests validates the posted manifests against the policy of the
+// resource before anything is created
+func (c *Controller) parseManifests(resource Resource, manifests string) ([]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+	for _, document := range strings.Split(manifests, "\n---") {
+		if strings.TrimSpace(document) == "" {
+			continue
+		}
+		jsonBytes, err := yaml.YAMLToJSON([]byte(document))
+		if err != nil {
+			return nil, err
+		}
+		object := &unstructured.Unstructured{}
+		err = json.Unmarshal(jsonBytes, object)
+		if err != nil {
+			return nil, err
+		}
+
+		if kindAllowed(resource.Manifest.AllowedKinds, object.GetKind()) != true {
+			return nil, fmt.Errorf("kind %s is not allowed by resource %s", object.GetKind(), resource.Name)
+		}
+		if c.imagePolicy != nil {
+			for _, image := range manifestImages(object.Object) {
+				err := c.validateImage(image)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		objects = append(objects, object)
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no manifests to apply")
+	}
+	return objects, nil
+}
+
+// kindAllowed checks a kind against the allow list of the backend
+func kindAllowed(allowed []string, kind string) bool {
+	if len(allowed) == 0 {
+		return false
+	}
+	for _, candidate := range allowed {
+		if strings.EqualFold(candidate, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// manifestImages collects every image field of a manifest, wherever nested
+func manifestImages(value interface{}) []string {
+	images := []string{}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			if key == "image" {
+				if image, ok := child.(string); ok {
+					images = append(images, image)
+					continue
+				}
+			}
+			images = append(images, manifestImages(child)...)
+		}
+	case []interface{}:
+		for _, child := range typed {
+			images = append(images, manifestImages(child)...)
+		}
+	}
+	return images
+}
+
+// manifestQuota builds the resource quota capping a passthrough namespace,
+// nil when the backend does not bound anything
+func manifestQuota(backend *ManifestBackend) (*apiv1.ResourceQuota, error) {
+	hard := apiv1.ResourceList{}
+	bounds := map[apiv1.ResourceName]string{
+		apiv1.ResourceCPU:    backend.CPU,
+		apiv1.ResourceMemory: backend.Memory,
+	}
+	for name, value := range bounds {
+		if value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, err
+		}
+		hard[name] = quantity
+	}
+	if len(hard) == 0 {
+		return nil, nil
+	}
+	return &apiv1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "manifest-quota"},
+		Spec:       apiv1.ResourceQuotaSpec{Hard: hard},
+	}, nil
+}
+
+// applyManifests creates the objects in the namespace of the instance
+func (c *Controller) applyManifests(objects []*unstructured.Unstructured, namespaceName string) error {
+	for _, object := range objects {
+		object.SetNamespace(namespaceName)
+		gvr, err := gvrForObject(object)
+		if err != nil {
+			return err
+		}
+		log.Printf("Applying %s %s into %s", object.GetKind(), object.GetName(), namespaceName)
+		_, err = c.dynamicClient.Resource(gvr).Namespace(namespaceName).Create(object, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gvrForObject guesses the resource name of an object from its kind, enough
+// for the common kinds a passthrough allows
+func gvrForObject(object *unstructured.Unstructured) (schema.GroupVersionResource, error) {
+	parts := strings.Split(object.GetAPIVersion(), "/")
+	group, version := "", parts[0]
+	if len(parts) == 2 {
+		group, version = parts[0], parts[1]
+	}
+	if version == "" {
+		return schema.GroupVersionResource{}, fmt.Errorf("manifest %s has no apiVersion", object.GetName())
+	}
+
+	kind := strings.ToLower(object.GetKind())
+	plural := kind + "s"
+	if strings.HasSuffix(kind, "y") {
+		plural = strings.TrimSuffix(kind, "y") + "ies"
+	} else if strings.HasSuffix(kind, "s") {
+		plural = kind + "es"
+	}
+	return schema.GroupVersionResource{Group: group, Version: version, Resource: plural}, nil
+}
@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// writeMetadataConfigMap maintains a ConfigMap workloads can mount to learn
+// who they belong to and when they disappear, enough to display a banner
+// like "this environment expires at ..." without talking to anything
+func (c *Controller) writeMetadataConfigMap(resource Resource, instance Instance) {
+	configMap := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ephemeral-metadata", Namespace: instance.Namespace},
+		Data: map[string]string{
+			"id":             instance.Namespace,
+			"owner":          instance.Owner,
+			"resource":       resource.Name,
+			"expiresAt":      time.Unix(instance.ExpirationDate, 0).Format(time.RFC3339),
+			"expiresAtEpoch": strconv.FormatInt(instance.ExpirationDate, 10),
+			"managerURL":     c.managerURL,
+		},
+	}
+	_, err := c.kubeClient.CoreV1().ConfigMaps(instance.Namespace).Create(configMap)
+	if errors.IsAlreadyExists(err) {
+		_, err = c.kubeClient.CoreV1().ConfigMaps(instance.Namespace).Update(configMap)
+	}
+	if err != nil {
+		log.Println("Error:", err)
+	}
+}
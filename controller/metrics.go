@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"github.com/geobeau/k8s-ephemeral-resources/metrics"
+)
+
+var (
+	stuckTerminating = metrics.NewGaugeVec("ephemeralresources_stuck_terminating",
+		"Number of instance namespaces stuck in Terminating per resource", "resource")
+
+	provisionQueueDepth = metrics.NewGaugeVec("ephemeralresources_provision_queue_depth",
+		"Number of creation requests waiting for a provisioning slot per resource", "resource")
+
+	cleanupLastSuccess = metrics.NewGaugeVec("ephemeralresources_cleanup_loop_last_success_timestamp",
+		"Unix timestamp of the last completed cleanup iteration")
+
+	cleanupIterationDuration = metrics.NewGaugeVec("ephemeralresources_cleanup_iteration_duration_seconds",
+		"Duration of the last cleanup iteration in seconds")
+
+	reapedTotal = metrics.NewCounterVec("ephemeralresources_reaped_total",
+		"Number of instances removed by the cleanup loop per resource and reason", "resource", "reason")
+
+	phaseTransitions = metrics.NewCounterVec("ephemeralresources_phase_transitions_total",
+		"Number of instance lifecycle transitions per resource and phase", "resource", "phase")
+)
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"strconv"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// MonitoringConfig opts the services of a resource into prometheus scraping
+type MonitoringConfig struct {
+	ScrapePort int    `yaml:"scrapePort"`
+	ScrapePath string `yaml:"scrapePath"`
+}
+
+// addMonitoring adds prometheus scrape annotations and identification labels
+// to an instance service, so ephemeral environments show up in the monitoring
+// stack and their series can be attributed to an owner and resource
+func addMonitoring(resource Resource, instance Instance, service *apiv1.Service) {
+	if resource.Monitoring == nil {
+		return
+	}
+
+	if service.Annotations == nil {
+		service.Annotations = make(map[string]string)
+	}
+	service.Annotations["prometheus.io/scrape"] = "true"
+	if resource.Monitoring.ScrapePort != 0 {
+		service.Annotations["prometheus.io/port"] = strconv.Itoa(resource.Monitoring.ScrapePort)
+	}
+	path := resource.Monitoring.ScrapePath
+	if path == "" {
+		path = "/metrics"
+	}
+	service.Annotations["prometheus.io/path"] = path
+
+	if service.Labels == nil {
+		service.Labels = make(map[string]string)
+	}
+	service.Labels["k8sEphemResourceName"] = resource.Name
+	service.Labels["k8sEphemResourceOwner"] = instance.Owner
+}
@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCConfig configures the OIDC authentication of the api and dashboard.
+// When present the Owner is derived from the authenticated identity instead
+// of trusting the JSON body, and delete/extend are restricted to the owners
+// of the instance and the admins
+type OIDCConfig struct {
+	// Issuer is the base url of the provider, used to discover the keys
+	Issuer string `yaml:"issuer"`
+	// Audience must appear in the aud claim, typically the client id
+	Audience string `yaml:"audience"`
+	// UsernameClaim is the claim used as the identity, "email" by default
+	UsernameClaim string `yaml:"usernameClaim"`
+	// GroupsClaim is the claim listing the groups, "groups" by default
+	GroupsClaim string `yaml:"groupsClaim"`
+	// AdminGroups can delete and extend any instance
+	AdminGroups []string `yaml:"adminGroups"`
+}
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	Username string
+	Groups   []string
+}
+
+// OIDCEnabled returns true when requests must carry a verified token
+func (c *Controller) OIDCEnabled() bool {
+	return c.oidc != nil
+}
+
+// IsAdmin returns true when the identity belongs to an admin group
+func (c *Controller) IsAdmin(identity Identity) bool {
+	if c.oidc == nil {
+		return false
+	}
+	for _, group := range identity.Groups {
+		for _, admin := range c.oidc.AdminGroups {
+			if group == admin {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Authenticate returns the identity of a request. Without OIDC configured it
+// falls back on the X-Identity header, trusted as before
+func (c *Controller) Authenticate(r *http.Request) (Identity, error) {
+	if c.oidc == nil {
+		return Identity{Username: r.Header.Get("X-Identity")}, nil
+	}
+	header := r.Header.Get("Authorization")
+	if strings.HasPrefix(header, "Bearer ") != true {
+		return Identity{}, errors.New("missing bearer token")
+	}
+	claims, err := c.verifyToken(strings.TrimPrefix(header, "Bearer "))
+	if err != nil {
+		return Identity{}, err
+	}
+
+	usernameClaim := c.oidc.UsernameClaim
+	if usernameClaim == "" {
+		usernameClaim = "email"
+	}
+	username, _ := claims[usernameClaim].(string)
+	if username == "" {
+		return Identity{}, fmt.Errorf("token misses the %s claim", usernameClaim)
+	}
+	groupsClaim := c.oidc.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	groups := []string{}
+	rawGroups, _ := claims[groupsClaim].([]interface{})
+	for _, group := range rawGroups {
+		name, ok := group.(string)
+		if ok {
+			groups = append(groups, name)
+		}
+	}
+	return Identity{Username: username, Groups: groups}, nil
+}
+
+// verifyToken checks the signature and the claims of an RS256 JWT against
+// the keys of the issuer. The provider exposes everything needed over plain
+// http, a client library is not worth the dependency
+func (c *Controller) verifyToken(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	err = json.Unmarshal(headerBytes, &header)
+	if err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported algorithm %s", header.Alg)
+	}
+
+	key, err := c.oidcKeys.key(c.oidc.Issuer, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature)
+	if err != nil {
+		return nil, errors.New("invalid token signature")
+	}
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	claims := map[string]interface{}{}
+	err = json.Unmarshal(payloadBytes, &claims)
+	if err != nil {
+		return nil, err
+	}
+	return claims, c.checkClaims(claims)
+}
+
+// checkClaims verifies the issuer, the audience and the expiry of a token
+func (c *Controller) checkClaims(claims map[string]interface{}) error {
+	issuer, _ := claims["iss"].(string)
+	if issuer != c.oidc.Issuer {
+		return fmt.Errorf("token issued by %s instead of %s", issuer, c.oidc.Issuer)
+	}
+	expiry, _ := claims["exp"].(float64)
+	if time.Now().Unix() >= int64(expiry) {
+		return errors.New("token expired")
+	}
+	if c.oidc.Audience == "" {
+		return nil
+	}
+	switch audience := claims["aud"].(type) {
+	case string:
+		if audience == c.oidc.Audience {
+			return nil
+		}
+	case []interface{}:
+		for _, entry := range audience {
+			if entry == c.oidc.Audience {
+				return nil
+			}
+		}
+	}
+	return errors.New("token issued for another audience")
+}
+
+// oidcKeyCache caches the signing keys of the issuer, refreshed when an
+// unknown key id shows up (a key rotation)
+type oidcKeyCache struct {
+	mutex sync.Mutex
+	keys  map[string]*rsa.PublicKey
+}
+
+// key returns the public key matching @kid, fetching the keys of @issuer on
+// a cache miss
+func (cache *oidcKeyCache) key(issuer string, kid string) (*rsa.PublicKey, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	key, ok := cache.keys[kid]
+	if ok {
+		return key, nil
+	}
+	err := cache.refresh(issuer)
+	if err != nil {
+		return nil, err
+	}
+	key, ok = cache.keys[kid]
+	if ok != true {
+		return nil, fmt.Errorf("unknown signing key %s", kid)
+	}
+	return key, nil
+}
+
+// refresh discovers the jwks endpoint of the issuer and reloads the keys
+func (cache *oidcKeyCache) refresh(issuer string) error {
+	discovery := struct {
+		JWKSURI string `json:"jwks_uri"`
+	}{}
+	err := fetchJSON(strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &discovery)
+	if err != nil {
+		return err
+	}
+
+	jwks := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	err = fetchJSON(discovery.JWKSURI, &jwks)
+	if err != nil {
+		return err
+	}
+
+	cache.keys = make(map[string]*rsa.PublicKey)
+	for _, key := range jwks.Keys {
+		if key.Kty != "RSA" {
+			continue
+		}
+		modulus, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			continue
+		}
+		exponent, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			continue
+		}
+		cache.keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(new(big.Int).SetBytes(exponent).Int64()),
+		}
+	}
+	return nil
+}
+
+// fetchJSON decodes the JSON answer of a GET request into @target
+func fetchJSON(url string, target interface{}) error {
+	response, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("%s answered %s", url, response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(target)
+}
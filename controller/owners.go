@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// coOwnersAnnotation stores the additional owners of an instance, comma
+// separated. Environments are often handed across timezones, so the original
+// owner alone should not gate extends and deletes
+const coOwnersAnnotation = "k8s-ephemeral-resources/co-owners"
+
+// UpdateOwners grants and revokes co-owners on an instance, returning the
+// resulting owner list. The primary owner cannot be removed
+func (c *Controller) UpdateOwners(name string, instanceID string, add []string, remove []string) ([]string, error) {
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return nil, fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+
+	primary := namespace.Labels["k8sEphemResourceOwner"]
+	coOwners := map[string]bool{}
+	for _, owner := range splitOwners(namespace.Annotations[coOwnersAnnotation]) {
+		coOwners[owner] = true
+	}
+	for _, owner := range add {
+		if owner == "" || owner == primary {
+			continue
+		}
+		coOwners[owner] = true
+	}
+	for _, owner := range remove {
+		if owner == primary {
+			return nil, fmt.Errorf("%s is the primary owner of %s and cannot be removed", owner, instanceID)
+		}
+		delete(coOwners, owner)
+	}
+
+	names := []string{}
+	for owner := range coOwners {
+		names = append(names, owner)
+	}
+	sort.Strings(names)
+
+	if namespace.Annotations == nil {
+		namespace.Annotations = map[string]string{}
+	}
+	if len(names) == 0 {
+		delete(namespace.Annotations, coOwnersAnnotation)
+	} else {
+		namespace.Annotations[coOwnersAnnotation] = strings.Join(names, ",")
+	}
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{primary}, names...), nil
+}
+
+// InstanceOwners returns the primary owner and the co-owners of an instance
+func (c *Controller) InstanceOwners(name string, instanceID string) ([]string, error) {
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return nil, fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+	owners := []string{namespace.Labels["k8sEphemResourceOwner"]}
+	return append(owners, splitOwners(namespace.Annotations[coOwnersAnnotation])...), nil
+}
+
+// IsInstanceOwner returns true when the identity is the owner or a co-owner
+// of the instance
+func (c *Controller) IsInstanceOwner(name string, instanceID string, identity string) (bool, error) {
+	owners, err := c.InstanceOwners(name, instanceID)
+	if err != nil {
+		return false, err
+	}
+	for _, owner := range owners {
+		if owner == identity {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// splitOwners parses the comma separated co-owners annotation
+func splitOwners(value string) []string {
+	owners := []string{}
+	for _, owner := range strings.Split(value, ",") {
+		if owner != "" {
+			owners = append(owners, owner)
+		}
+	}
+	return owners
+}
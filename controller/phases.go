@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// phaseHistoryAnnotation keeps the lifecycle timeline of an instance on its
+// namespace, so slow environments can be debugged and audits answered
+// without an external store
+const phaseHistoryAnnotation = "k8s-ephemeral-resources/phase-history"
+
+// PhaseEvent is one transition in the lifecycle of an instance
+type PhaseEvent struct {
+	Phase string `json:"phase"`
+	At    string `json:"at"`
+}
+
+// appendPhases appends transitions to the timeline of an instance, best
+// effort: a lost phase must never fail the operation that caused it
+func (c *Controller) appendPhases(resourceName string, namespaceName string, events []PhaseEvent) {
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(namespaceName, metav1.GetOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	history := []PhaseEvent{}
+	existing, ok := namespace.Annotations[phaseHistoryAnnotation]
+	if ok {
+		err = json.Unmarshal([]byte(existing), &history)
+		if err != nil {
+			log.Println("Error:", err)
+		}
+	}
+	history = append(history, events...)
+	payload, err := json.Marshal(history)
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{phaseHistoryAnnotation: string(payload)},
+		},
+	})
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	_, err = c.kubeClient.CoreV1().Namespaces().Patch(namespaceName, types.StrategicMergePatchType, patch)
+	if err != nil {
+		log.Println("Error:", err)
+	}
+	for _, event := range events {
+		phaseTransitions.WithLabelValues(resourceName, event.Phase).Inc()
+	}
+}
+
+// recordPhase appends a single transition happening now
+func (c *Controller) recordPhase(resourceName string, namespaceName string, phase string) {
+	c.appendPhases(resourceName, namespaceName, []PhaseEvent{phaseEvent(phase, time.Now())})
+}
+
+// phaseEvent builds one transition
+func phaseEvent(phase string, at time.Time) PhaseEvent {
+	return PhaseEvent{Phase: phase, At: at.Format(time.RFC3339)}
+}
+
+// PhaseHistory returns the lifecycle timeline of an instance
+func (c *Controller) PhaseHistory(name string, instanceID string) ([]PhaseEvent, error) {
+	_, ok := c.Resources[name]
+	if ok != true {
+		return nil, fmt.Errorf("Resource Not found")
+	}
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return nil, fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+	history := []PhaseEvent{}
+	existing, ok := namespace.Annotations[phaseHistoryAnnotation]
+	if ok {
+		err = json.Unmarshal([]byte(existing), &history)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/geobeau/k8s-ephemeral-resources/clock"
+	"github.com/geobeau/k8s-ephemeral-resources/tracing"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PoolLoop wakes up every @delay to backfill the pools of pre-warmed instances
+func (c *Controller) PoolLoop(delay time.Duration) {
+	for {
+		for _, resource := range c.Resources {
+			if resource.PoolSize > 0 && c.ownsInstance(resource.Name) {
+				c.refillPool(resource)
+			}
+		}
+		time.Sleep(delay)
+	}
+}
+
+// refillPool provisions unassigned instances until the pool is full
+func (c *Controller) refillPool(resource Resource) {
+	listOptions := metav1.ListOptions{LabelSelector: "k8sEphemResourceName=" + resource.Name + ",k8sEphemResourcePooled=true"}
+	list, err := c.kubeClient.CoreV1().Namespaces().List(listOptions)
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+
+	storageSize, storageClass, err := c.resolveStorage(resource, CreateOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+
+	missing := resource.PoolSize - len(list.Items)
+	for i := 0; i < missing; i++ {
+		instance := Instance{Namespace: c.newIdentifier(resource, c.prefix), StorageSize: storageSize, StorageClass: storageClass}
+		labels := make(map[string]string)
+		labels["k8sEphemResourceName"] = resource.Name
+		labels["k8sEphemResourcePooled"] = "true"
+		labels["k8sEphemResourceTemplateVersion"] = templateVersion(resource)
+
+		log.Printf("Backfilling pool of %s (%d/%d)", resource.Name, resource.PoolSize-missing+i+1, resource.PoolSize)
+		span := tracing.StartSpan("refill-pool")
+		span.SetAttribute("resource", resource.Name)
+		err := c.provisionInstance(resource, instance, labels, span)
+		span.End()
+		if err != nil {
+			log.Println("Error:", err)
+			return
+		}
+	}
+}
+
+// claimPooledInstance assigns a pre-warmed instance to an owner. It returns
+// false when the pool is empty
+func (c *Controller) claimPooledInstance(resource Resource, options CreateOptions, ttl time.Duration) (Instance, bool) {
+	listOptions := metav1.ListOptions{LabelSelector: "k8sEphemResourceName=" + resource.Name + ",k8sEphemResourcePooled=true"}
+	list, err := c.kubeClient.CoreV1().Namespaces().List(listOptions)
+	if err != nil {
+		log.Println("Error:", err)
+		return Instance{}, false
+	}
+
+	for _, namespace := range list.Items {
+		instance := Instance{
+			Namespace: namespace.Name,
+			Owner: options.Owner,
+			ExpirationDate: clock.Now().Add(ttl).Unix(),
+		}
+		delete(namespace.Labels, "k8sEphemResourcePooled")
+		namespace.Labels["k8sEphemResourceExpirationDate"] = strconv.FormatInt(instance.ExpirationDate, 10)
+		namespace.Labels["k8sEphemResourceOwner"] = options.Owner
+		if options.Tenant != "" {
+			namespace.Labels["k8sEphemResourceTenant"] = options.Tenant
+		}
+		if options.Lifecycle == "heartbeat" {
+			namespace.Labels["k8sEphemResourceLifecycle"] = "heartbeat"
+			namespace.Labels["k8sEphemResourceLastHeartbeat"] = strconv.FormatInt(clock.Now().Unix(), 10)
+		}
+
+		log.Println("Claiming pooled instance: ", namespace.Name)
+		_, err := c.kubeClient.CoreV1().Namespaces().Update(&namespace)
+		if err != nil {
+			log.Println("Error:", err)
+			continue
+		}
+		c.nsCache.bust()
+		return instance, true
+	}
+
+	return Instance{}, false
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var prefixPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,19}$`)
+
+// ValidateNamespacePrefix checks that a namespace prefix produces valid
+// namespace names once a resource name and an identifier are appended
+func ValidateNamespacePrefix(prefix string) error {
+	if prefixPattern.MatchString(prefix) != true {
+		return fmt.Errorf("invalid namespace prefix %q: lowercase alphanumerics and dashes only, 20 characters at most", prefix)
+	}
+	return nil
+}
+
+// ScanPrefixCollisions warns about namespaces that match the instance prefix
+// but are not tracked instances, either orphans of a previous deployment or
+// foreign namespaces the cleanup could collide with
+func (c *Controller) ScanPrefixCollisions() {
+	list, err := c.kubeClient.CoreV1().Namespaces().List(metav1.ListOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+
+	prefixes := []string{c.prefix}
+	for _, resource := range c.Resources {
+		if resource.NamespacePrefix != "" {
+			prefixes = append(prefixes, resource.NamespacePrefix)
+		}
+	}
+
+	for _, namespace := range list.Items {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(namespace.Name, prefix) != true {
+				continue
+			}
+			_, tracked := namespace.Labels["k8sEphemResourceName"]
+			if tracked != true {
+				log.Printf("Warning: namespace %s matches the prefix %s but is not a tracked instance", namespace.Name, prefix)
+			}
+			break
+		}
+	}
+}
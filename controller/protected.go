@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"os"
+)
+
+// systemNamespaces are never touched by the cleanup loop or the delete
+// APIs, whatever their labels pretend
+var systemNamespaces = []string{"default", "kube-system", "kube-public", "kube-node-lease"}
+
+// protectedNamespaces builds the hard safety list: the system namespaces,
+// the namespace the manager runs in and the configured extras
+func protectedNamespaces(config Config) map[string]bool {
+	protected := map[string]bool{}
+	for _, name := range systemNamespaces {
+		protected[name] = true
+	}
+	if own := os.Getenv("POD_NAMESPACE"); own != "" {
+		protected[own] = true
+	}
+	for _, name := range config.ProtectedNamespaces {
+		protected[name] = true
+	}
+	return protected
+}
+
+// isProtected tells whether a namespace must never be deleted
+func (c *Controller) isProtected(namespaceName string) bool {
+	return c.protected[namespaceName]
+}
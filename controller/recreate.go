@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RecreatedInstance maps an outdated instance to its replacement
+type RecreatedInstance struct {
+	Old string
+	New string
+}
+
+// RecreateOutdated replaces up to @batchSize instances built from an old
+// template version. The replacement is provisioned first and the outdated
+// instance is only marked for deletion, so the owner keeps the undo window to
+// react. Called repeatedly, it rolls a template fix over the whole fleet
+// batch by batch
+func (c *Controller) RecreateOutdated(name string, batchSize int) ([]RecreatedInstance, error) {
+	outdated, err := c.OutdatedInstances(name)
+	if err != nil {
+		return nil, err
+	}
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	if len(outdated) > batchSize {
+		outdated = outdated[:batchSize]
+	}
+
+	recreated := []RecreatedInstance{}
+	for _, old := range outdated {
+		namespace, err := c.kubeClient.CoreV1().Namespaces().Get(old.Namespace, metav1.GetOptions{})
+		if err != nil {
+			return recreated, err
+		}
+		options := CreateOptions{
+			Owner: namespace.Labels["k8sEphemResourceOwner"],
+			Tenant: namespace.Labels["k8sEphemResourceTenant"],
+			Lifecycle: namespace.Labels["k8sEphemResourceLifecycle"],
+		}
+
+		instance, err := c.CreateNewInstance(name, options)
+		if err != nil {
+			return recreated, fmt.Errorf("could not recreate %s: %v", old.Namespace, err)
+		}
+		_, err = c.MarkInstanceForDeletion(name, old.Namespace)
+		if err != nil {
+			return recreated, err
+		}
+		log.Printf("Recreated %s as %s for owner %s", old.Namespace, instance.Namespace, options.Owner)
+		recreated = append(recreated, RecreatedInstance{Old: old.Namespace, New: instance.Namespace})
+	}
+	return recreated, nil
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"errors"
+	"time"
+
+	"github.com/geobeau/k8s-ephemeral-resources/clock"
+)
+
+// RenderInstance renders the manifests an instance creation would apply,
+// without creating anything. Resource authors use it to lint their templates
+func (c *Controller) RenderInstance(name string, options CreateOptions) (map[string]string, error) {
+	resource, ok := c.Resources[name]
+	if ok != true {
+		return nil, errors.New("Resource Not found")
+	}
+
+	storageSize, storageClass, err := c.resolveStorage(resource, options)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := c.tenants[options.Tenant]
+	instance := Instance{
+		Namespace: c.newIdentifier(resource, c.tenantPrefix(tenant)),
+		Owner: options.Owner,
+		ExpirationDate: clock.Now().Add(time.Duration(resource.DurationDefault)).Unix(),
+		StorageSize: storageSize,
+		StorageClass: storageClass,
+	}
+
+	manifests := make(map[string]string)
+
+	components := resource.Components
+	if len(components) == 0 {
+		components = []string{resource.Name}
+	}
+	for _, componentName := range components {
+		component, ok := c.Resources[componentName]
+		if ok != true {
+			return nil, errors.New("unknown component " + componentName + " in composite resource " + name)
+		}
+		deployment, err := instance.generateConfigFromTemplate(component.DeploymentTemplate)
+		if err != nil {
+			return nil, err
+		}
+		manifests["deployment/"+componentName] = deployment
+
+		service, err := instance.generateConfigFromTemplate(component.ServiceTemplate)
+		if err != nil {
+			return nil, err
+		}
+		manifests["service/"+componentName] = service
+	}
+
+	if options.Fixture != "" {
+		source, ok := resource.Fixtures[options.Fixture]
+		if ok != true {
+			return nil, errors.New("unknown fixture " + options.Fixture + " for resource " + name)
+		}
+		context := seedContext{Instance: instance, FixtureName: options.Fixture, FixtureSource: source}
+		seedJob, err := renderTemplate(instance.Namespace, resource.SeedJobTemplate, context)
+		if err != nil {
+			return nil, err
+		}
+		manifests["job/seed"] = seedJob
+	}
+
+	return manifests, nil
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ResizeConfig bounds the vertical resizing of instance workloads
+type ResizeConfig struct {
+	MaxCPU    string `yaml:"maxCpu"`
+	MaxMemory string `yaml:"maxMemory"`
+}
+
+// ResizeRequest is the caller supplied CPU/memory adjustment
+type ResizeRequest struct {
+	CPURequest    string
+	CPULimit      string
+	MemoryRequest string
+	MemoryLimit   string
+}
+
+// ResizeInstance adjusts the CPU/memory of the instance workloads within the
+// resource bounds, triggering a rolling restart of the deployments
+func (c *Controller) ResizeInstance(name string, instanceID string, request ResizeRequest) error {
+	res, ok := c.Resources[name]
+	if ok != true {
+		return errors.New("Resource Not found")
+	}
+	if res.Resize == nil {
+		return fmt.Errorf("resource %s does not support resizing", name)
+	}
+
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+
+	requests := apiv1.ResourceList{}
+	limits := apiv1.ResourceList{}
+	err = addQuantity(requests, apiv1.ResourceCPU, request.CPURequest, res.Resize.MaxCPU)
+	if err != nil {
+		return err
+	}
+	err = addQuantity(limits, apiv1.ResourceCPU, request.CPULimit, res.Resize.MaxCPU)
+	if err != nil {
+		return err
+	}
+	err = addQuantity(requests, apiv1.ResourceMemory, request.MemoryRequest, res.Resize.MaxMemory)
+	if err != nil {
+		return err
+	}
+	err = addQuantity(limits, apiv1.ResourceMemory, request.MemoryLimit, res.Resize.MaxMemory)
+	if err != nil {
+		return err
+	}
+	if len(requests) == 0 && len(limits) == 0 {
+		return errors.New("nothing to resize")
+	}
+
+	deployments, err := c.kubeClient.AppsV1beta2().Deployments(instanceID).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, deployment := range deployments.Items {
+		for i := range deployment.Spec.Template.Spec.Containers {
+			container := &deployment.Spec.Template.Spec.Containers[i]
+			if container.Resources.Requests == nil {
+				container.Resources.Requests = apiv1.ResourceList{}
+			}
+			if container.Resources.Limits == nil {
+				container.Resources.Limits = apiv1.ResourceList{}
+			}
+			for resourceName, quantity := range requests {
+				container.Resources.Requests[resourceName] = quantity
+			}
+			for resourceName, quantity := range limits {
+				container.Resources.Limits[resourceName] = quantity
+			}
+		}
+		log.Printf("Resizing deployment %s/%s", instanceID, deployment.Name)
+		_, err = c.kubeClient.AppsV1beta2().Deployments(instanceID).Update(&deployment)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addQuantity parses a requested quantity, checks it against the resource
+// bound and stores it in the resource list
+func addQuantity(list apiv1.ResourceList, name apiv1.ResourceName, value string, max string) error {
+	if value == "" {
+		return nil
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s quantity %s: %v", name, value, err)
+	}
+	if max != "" {
+		maxQuantity, err := resource.ParseQuantity(max)
+		if err != nil {
+			return err
+		}
+		if quantity.Cmp(maxQuantity) > 0 {
+			return fmt.Errorf("%s %s is above the maximum %s", name, value, max)
+		}
+	}
+	list[name] = quantity
+	return nil
+}
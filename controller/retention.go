@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/geobeau/k8s-ephemeral-resources/clock"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RetentionConfig makes expiry a two-phase process: workloads are scaled to
+// zero first and the namespace is only deleted once the retention window has
+// passed, leaving a chance to resurrect the data
+type RetentionConfig struct {
+	Window time.Duration `yaml:"window"`
+}
+
+// retainInsteadOfDelete handles the expiry of an instance with a retention
+// config. Returns true while the instance must be kept: either it was just
+// scaled down or the retention window is still running
+func (c *Controller) retainInsteadOfDelete(resource Resource, namespace *apiv1.Namespace) bool {
+	scaledAtStr, ok := namespace.Labels["k8sEphemResourceScaledDownAt"]
+	if ok != true {
+		err := c.scaleDownInstance(namespace)
+		if err != nil {
+			log.Println("Error:", err)
+		}
+		return true
+	}
+
+	scaledAtEpoch, err := strconv.ParseInt(scaledAtStr, 10, 64)
+	if err != nil {
+		log.Println("Error:", err)
+		return false
+	}
+	return clock.Now().Before(time.Unix(scaledAtEpoch, 0).Add(resource.Retention.Window))
+}
+
+// scaleDownInstance scales every deployment of an instance to zero, keeping
+// the previous replica count in an annotation for a later resurrection
+func (c *Controller) scaleDownInstance(namespace *apiv1.Namespace) error {
+	log.Printf("Scaling %s to zero, deletion after the retention window", namespace.Name)
+	deployments, err := c.kubeClient.AppsV1beta2().Deployments(namespace.Name).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	zero := int32(0)
+	for _, deployment := range deployments.Items {
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+		if deployment.Annotations == nil {
+			deployment.Annotations = map[string]string{}
+		}
+		deployment.Annotations["k8s-ephemeral-resources/original-replicas"] = strconv.Itoa(int(replicas))
+		deployment.Spec.Replicas = &zero
+		_, err = c.kubeClient.AppsV1beta2().Deployments(namespace.Name).Update(&deployment)
+		if err != nil {
+			return err
+		}
+	}
+
+	namespace.Labels["k8sEphemResourceScaledDownAt"] = strconv.FormatInt(clock.Now().Unix(), 10)
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	return err
+}
+
+// ResurrectInstance scales a retained instance back up and gives it a fresh
+// TTL
+func (c *Controller) ResurrectInstance(name string, instanceID string) error {
+	resource, ok := c.Resources[name]
+	if ok != true {
+		return fmt.Errorf("Resource Not found")
+	}
+
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+	_, scaledDown := namespace.Labels["k8sEphemResourceScaledDownAt"]
+	if scaledDown != true {
+		return fmt.Errorf("%s is not scaled down", instanceID)
+	}
+
+	deployments, err := c.kubeClient.AppsV1beta2().Deployments(instanceID).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, deployment := range deployments.Items {
+		replicas := int32(1)
+		original, err := strconv.Atoi(deployment.Annotations["k8s-ephemeral-resources/original-replicas"])
+		if err == nil && original > 0 {
+			replicas = int32(original)
+		}
+		deployment.Spec.Replicas = &replicas
+		delete(deployment.Annotations, "k8s-ephemeral-resources/original-replicas")
+		_, err = c.kubeClient.AppsV1beta2().Deployments(instanceID).Update(&deployment)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Resurrecting %s with a fresh TTL", instanceID)
+	delete(namespace.Labels, "k8sEphemResourceScaledDownAt")
+	namespace.Labels["k8sEphemResourceExpirationDate"] = strconv.FormatInt(clock.Now().Add(time.Duration(resource.DurationDefault)).Unix(), 10)
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	c.nsCache.bust()
+	return err
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"log"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/geobeau/k8s-ephemeral-resources/tracing"
+)
+
+// provisionRetries bounds how many times one provisioning attempt is retried
+// before the error surfaces to the api user
+const provisionRetries = 3
+
+// provisionWithRetry runs the backend provisioning, absorbing the errors the
+// user cannot act on. An AlreadyExists answer means a shortuuid collision or
+// a leftover namespace: a fresh identifier costs nothing, so regenerate and
+// retry transparently. Other errors are usually a transient apiserver
+// hiccup, worth a couple of retries with backoff before failing the request
+func (c *Controller) provisionWithRetry(resource Resource, instance *Instance, labels map[string]string, span *tracing.Span, tenant Tenant) error {
+	backoff := 500 * time.Millisecond
+	var err error
+	for attempt := 1; attempt <= provisionRetries; attempt++ {
+		err = c.provision(resource, *instance, labels, span)
+		if err == nil {
+			return nil
+		}
+		if errors.IsAlreadyExists(err) {
+			log.Printf("Identifier %s already exists, regenerating", instance.Namespace)
+			instance.Namespace = c.newIdentifier(resource, c.tenantPrefix(tenant))
+			continue
+		}
+		if attempt < provisionRetries {
+			log.Printf("Provisioning of %s failed (%v), retrying in %s", instance.Namespace, err, backoff)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"log"
+
+	apiv1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SandboxConfig declares a resource as a personal sandbox: an empty namespace
+// with a quota and an admin binding for the requesting user, without any
+// workload templates
+type SandboxConfig struct {
+	CPU    string `yaml:"cpu"`
+	Memory string `yaml:"memory"`
+	Pods   string `yaml:"pods"`
+}
+
+// provisionSandbox creates the namespace of a sandbox with its quota and the
+// admin role binding of the owner
+func (c *Controller) provisionSandbox(resource Resource, instance Instance, labels map[string]string) error {
+	identifier := instance.Namespace
+	namespace := &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: identifier, Labels: labels}}
+
+	log.Println("Creating sandbox namespace: ", identifier)
+
+	_, err := c.kubeClient.CoreV1().Namespaces().Create(namespace)
+	if err != nil {
+		return err
+	}
+
+	quota, err := sandboxQuota(resource.Sandbox)
+	if err != nil {
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
+	}
+	if quota != nil {
+		_, err = c.kubeClient.CoreV1().ResourceQuotas(identifier).Create(quota)
+		if err != nil {
+			log.Println("Error while create resource, removing namespace")
+			c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+			return err
+		}
+	}
+
+	roleBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "sandbox-owner"},
+		Subjects: []rbacv1.Subject{
+			{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: instance.Owner},
+		},
+		RoleRef: rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "admin"},
+	}
+	_, err = c.kubeClient.RbacV1().RoleBindings(identifier).Create(roleBinding)
+	if err != nil {
+		log.Println("Error while create resource, removing namespace")
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
+	}
+	return nil
+}
+
+// sandboxQuota builds the resource quota of a sandbox, nil when the config
+// does not bound anything
+func sandboxQuota(config *SandboxConfig) (*apiv1.ResourceQuota, error) {
+	hard := apiv1.ResourceList{}
+	bounds := map[apiv1.ResourceName]string{
+		apiv1.ResourceCPU:    config.CPU,
+		apiv1.ResourceMemory: config.Memory,
+		apiv1.ResourcePods:   config.Pods,
+	}
+	for name, value := range bounds {
+		if value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, err
+		}
+		hard[name] = quantity
+	}
+	if len(hard) == 0 {
+		return nil, nil
+	}
+	return &apiv1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "sandbox-quota"},
+		Spec:       apiv1.ResourceQuotaSpec{Hard: hard},
+	}, nil
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// ScannerConfig points at the vulnerability scanner instances images are
+// checked against, any service speaking the simple scan contract works
+// (POST {"image": ...} answering {"vulnerabilities": [{"id", "severity"}]})
+type ScannerConfig struct {
+	URL string `yaml:"url"`
+}
+
+// ScanConfig holds ephemeral environments to the security bar: block or
+// warn when the scanner reports vulnerabilities at failOn severity or above
+type ScanConfig struct {
+	FailOn string `yaml:"failOn"`
+	Warn   bool   `yaml:"warn"`
+}
+
+// Vulnerability is one finding of the scanner
+type Vulnerability struct {
+	ID       string `json:"id"`
+	Severity string `json:"severity"`
+}
+
+var severityRank = map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+
+// scanImages checks the images of an instance against the scanner and fails
+// the creation when the resource blocks on findings
+func (c *Controller) scanImages(resource Resource, images []string) error {
+	if resource.Scan == nil || c.scanner == nil {
+		return nil
+	}
+
+	threshold := severityRank[strings.ToLower(resource.Scan.FailOn)]
+	if threshold == 0 {
+		threshold = severityRank["critical"]
+	}
+
+	for _, image := range images {
+		findings, err := c.scanImage(image)
+		if err != nil {
+			log.Printf("Could not scan %s: %v", image, err)
+			continue
+		}
+		for _, vulnerability := range findings {
+			if severityRank[strings.ToLower(vulnerability.Severity)] < threshold {
+				continue
+			}
+			if resource.Scan.Warn {
+				log.Printf("Warning: %s is affected by %s (%s)", image, vulnerability.ID, vulnerability.Severity)
+				continue
+			}
+			return fmt.Errorf("image %s is affected by %s (%s), creation blocked", image, vulnerability.ID, vulnerability.Severity)
+		}
+	}
+	return nil
+}
+
+// podImages lists the images of a rendered pod spec
+func podImages(spec *apiv1.PodSpec) []string {
+	images := []string{}
+	for _, container := range spec.InitContainers {
+		images = append(images, container.Image)
+	}
+	for _, container := range spec.Containers {
+		images = append(images, container.Image)
+	}
+	return images
+}
+
+// scanImage asks the scanner for the vulnerabilities of one image
+func (c *Controller) scanImage(image string) ([]Vulnerability, error) {
+	payload, err := json.Marshal(map[string]string{"image": image})
+	if err != nil {
+		return nil, err
+	}
+	response, err := http.Post(c.scanner.URL, "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return nil, fmt.Errorf("scanner answered %s", response.Status)
+	}
+
+	report := struct {
+		Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+	}{}
+	err = json.NewDecoder(response.Body).Decode(&report)
+	if err != nil {
+		return nil, err
+	}
+	return report.Vulnerabilities, nil
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule restricts when new instances can be created
+type Schedule struct {
+	AllowedWindows []Window `yaml:"allowedWindows"`
+	Freezes        []Freeze `yaml:"freezes"`
+}
+
+// Window is a recurring weekly window during which creation is allowed
+type Window struct {
+	Days  []string `yaml:"days"`  // lowercase weekday names, empty for every day
+	Start string   `yaml:"start"` // like "08:00"
+	End   string   `yaml:"end"`   // like "18:00"
+}
+
+// Freeze is an absolute period during which creation is forbidden
+type Freeze struct {
+	From   string `yaml:"from"` // RFC3339
+	To     string `yaml:"to"`
+	Reason string `yaml:"reason"`
+}
+
+// FrozenError is returned when the schedule forbids creating an instance
+type FrozenError struct {
+	Reason      string
+	NextAllowed time.Time
+}
+
+func (e *FrozenError) Error() string {
+	return fmt.Sprintf("creation is frozen: %s, next allowed time: %s", e.Reason, e.NextAllowed.Format(time.RFC3339))
+}
+
+// CheckCreationAllowed returns a FrozenError when creation is currently
+// forbidden by a freeze or outside of every allowed window
+func (s *Schedule) CheckCreationAllowed(now time.Time) error {
+	for _, freeze := range s.Freezes {
+		from, err := time.Parse(time.RFC3339, freeze.From)
+		if err != nil {
+			return fmt.Errorf("invalid freeze start %s: %v", freeze.From, err)
+		}
+		to, err := time.Parse(time.RFC3339, freeze.To)
+		if err != nil {
+			return fmt.Errorf("invalid freeze end %s: %v", freeze.To, err)
+		}
+		if now.After(from) && now.Before(to) {
+			reason := freeze.Reason
+			if reason == "" {
+				reason = "maintenance freeze"
+			}
+			return &FrozenError{Reason: reason, NextAllowed: to}
+		}
+	}
+
+	if len(s.AllowedWindows) == 0 {
+		return nil
+	}
+
+	for _, window := range s.AllowedWindows {
+		inside, err := window.contains(now)
+		if err != nil {
+			return err
+		}
+		if inside {
+			return nil
+		}
+	}
+
+	next, err := s.nextWindowStart(now)
+	if err != nil {
+		return err
+	}
+	return &FrozenError{Reason: "outside of allowed creation windows", NextAllowed: next}
+}
+
+// contains returns true when @now falls inside the window
+func (w *Window) contains(now time.Time) (bool, error) {
+	start, end, err := w.clockBounds(now)
+	if err != nil {
+		return false, err
+	}
+	// A window whose end precedes its start crosses midnight: it opens on
+	// each listed day and runs into the morning of the next one
+	if end.Before(start) {
+		if w.matchesDay(now) && now.After(start) {
+			return true, nil
+		}
+		return w.matchesDay(now.AddDate(0, 0, -1)) && now.Before(end), nil
+	}
+	if w.matchesDay(now) != true {
+		return false, nil
+	}
+	return now.After(start) && now.Before(end), nil
+}
+
+// matchesDay returns true when the weekday of @now is listed in the window
+func (w *Window) matchesDay(now time.Time) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	day := strings.ToLower(now.Weekday().String())
+	for _, allowed := range w.Days {
+		if strings.ToLower(allowed) == day {
+			return true
+		}
+	}
+	return false
+}
+
+// clockBounds returns the start and end of the window on the day of @now
+func (w *Window) clockBounds(now time.Time) (time.Time, time.Time, error) {
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid window start %s: %v", w.Start, err)
+	}
+	end, err := time.Parse("15:04", w.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid window end %s: %v", w.End, err)
+	}
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return day.Add(time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute),
+		day.Add(time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute), nil
+}
+
+// nextWindowStart returns the earliest window start after @now
+func (s *Schedule) nextWindowStart(now time.Time) (time.Time, error) {
+	var next time.Time
+	for offset := 0; offset <= 7; offset++ {
+		day := now.AddDate(0, 0, offset)
+		for _, window := range s.AllowedWindows {
+			if window.matchesDay(day) != true {
+				continue
+			}
+			start, _, err := window.clockBounds(day)
+			if err != nil {
+				return time.Time{}, err
+			}
+			if start.After(now) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+	}
+	return next, nil
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ParamError is a field-level validation error of a create request
+type ParamError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidateParams checks the params of a create request against the JSON
+// Schema declared by the resource, answering field-level errors. Resources
+// without a schema accept no params
+func (c *Controller) ValidateParams(name string, params map[string]interface{}) []ParamError {
+	resource, ok := c.Resources[name]
+	if ok != true {
+		return nil
+	}
+	if resource.ParamsSchema == nil {
+		if len(params) > 0 {
+			return []ParamError{{Field: "params", Message: "resource " + name + " does not accept params"}}
+		}
+		return nil
+	}
+
+	errors := []ParamError{}
+	validateObject(resource.ParamsSchema, params, "", &errors)
+	return errors
+}
+
+// validateObject checks a map against an object schema
+func validateObject(schema map[string]interface{}, params map[string]interface{}, path string, errors *[]ParamError) {
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, field := range required {
+			name, _ := field.(string)
+			if _, present := params[name]; present != true {
+				*errors = append(*errors, ParamError{Field: fieldPath(path, name), Message: "is required"})
+			}
+		}
+	}
+
+	additional, declared := schema["additionalProperties"].(bool)
+	for name, value := range params {
+		property, known := properties[name].(map[string]interface{})
+		if known != true {
+			if declared && additional != true {
+				*errors = append(*errors, ParamError{Field: fieldPath(path, name), Message: "is not a declared param"})
+			}
+			continue
+		}
+		validateValue(property, value, fieldPath(path, name), errors)
+	}
+}
+
+// validateValue checks one value against its property schema
+func validateValue(schema map[string]interface{}, value interface{}, path string, errors *[]ParamError) {
+	expected, _ := schema["type"].(string)
+	switch expected {
+	case "string":
+		text, ok := value.(string)
+		if ok != true {
+			*errors = append(*errors, ParamError{Field: path, Message: "must be a string"})
+			return
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			matcher, err := regexp.Compile(pattern)
+			if err == nil && matcher.MatchString(text) != true {
+				*errors = append(*errors, ParamError{Field: path, Message: "must match " + pattern})
+			}
+		}
+	case "integer", "number":
+		number, ok := asNumber(value)
+		if ok != true {
+			*errors = append(*errors, ParamError{Field: path, Message: "must be a number"})
+			return
+		}
+		if minimum, ok := asNumber(schema["minimum"]); ok && number < minimum {
+			*errors = append(*errors, ParamError{Field: path, Message: fmt.Sprintf("must be at least %v", schema["minimum"])})
+		}
+		if maximum, ok := asNumber(schema["maximum"]); ok && number > maximum {
+			*errors = append(*errors, ParamError{Field: path, Message: fmt.Sprintf("must be at most %v", schema["maximum"])})
+		}
+	case "boolean":
+		if _, ok := value.(bool); ok != true {
+			*errors = append(*errors, ParamError{Field: path, Message: "must be a boolean"})
+			return
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if ok != true {
+			*errors = append(*errors, ParamError{Field: path, Message: "must be an object"})
+			return
+		}
+		validateObject(schema, object, path, errors)
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		options := []string{}
+		for _, option := range enum {
+			if fmt.Sprintf("%v", option) == fmt.Sprintf("%v", value) {
+				return
+			}
+			options = append(options, fmt.Sprintf("%v", option))
+		}
+		*errors = append(*errors, ParamError{Field: path, Message: "must be one of " + strings.Join(options, ", ")})
+	}
+}
+
+// asNumber normalizes the numeric types JSON and YAML decoding produce
+func asNumber(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case float64:
+		return number, true
+	case int:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	}
+	return 0, false
+}
+
+// fieldPath joins a nested field path
+func fieldPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lithammer/shortuuid"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ShardingConfig shards the instance ownership between manager replicas.
+// Each replica announces itself through a Lease and instances are assigned
+// with rendezvous hashing over the live members, so cleanup scales
+// horizontally instead of every replica scanning everything
+type ShardingConfig struct {
+	LeaseNamespace string `yaml:"leaseNamespace"`
+}
+
+const shardLeaseDuration = 60 * time.Second
+
+// newShardMemberID returns a stable identity for this replica
+func newShardMemberID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "manager"
+	}
+	return fmt.Sprintf("%s-%s", hostname, strings.ToLower(shortuuid.New())[:8])
+}
+
+// ShardLoop keeps the Lease of this replica renewed so the other members
+// keep assigning it a share of the instances
+func (c *Controller) ShardLoop() {
+	if c.sharding == nil {
+		return
+	}
+	for {
+		err := c.renewShardLease()
+		if err != nil {
+			log.Println("Error:", err)
+		}
+		time.Sleep(shardLeaseDuration / 4)
+	}
+}
+
+// renewShardLease creates or renews the Lease announcing this replica
+func (c *Controller) renewShardLease() error {
+	leases := c.kubeClient.CoordinationV1().Leases(c.sharding.config.LeaseNamespace)
+	now := metav1.NewMicroTime(time.Now())
+	seconds := int32(shardLeaseDuration.Seconds())
+	name := "k8s-ephem-shard-" + c.sharding.memberID
+
+	lease, err := leases.Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{"k8sEphemShardMember": "true"},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &c.sharding.memberID,
+				LeaseDurationSeconds: &seconds,
+				RenewTime:            &now,
+			},
+		}
+		_, err = leases.Create(lease)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(lease)
+	return err
+}
+
+// shardMembers returns the identities of the replicas holding a fresh Lease
+func (c *Controller) shardMembers() ([]string, error) {
+	leases, err := c.kubeClient.CoordinationV1().Leases(c.sharding.config.LeaseNamespace).List(metav1.ListOptions{LabelSelector: "k8sEphemShardMember=true"})
+	if err != nil {
+		return nil, err
+	}
+	members := []string{}
+	for _, lease := range leases.Items {
+		if lease.Spec.HolderIdentity == nil || lease.Spec.RenewTime == nil {
+			continue
+		}
+		if time.Since(lease.Spec.RenewTime.Time) > shardLeaseDuration {
+			continue
+		}
+		members = append(members, *lease.Spec.HolderIdentity)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// ownsInstance reports whether this replica is responsible for a namespace.
+// Rendezvous hashing keeps the assignment stable when members come and go.
+// Everything is owned when sharding is disabled or the members cannot be
+// listed, trading duplicate work for not leaking instances
+func (c *Controller) ownsInstance(namespaceName string) bool {
+	if c.sharding == nil {
+		return true
+	}
+	members, err := c.shardMembers()
+	if err != nil {
+		log.Println("Error:", err)
+		return true
+	}
+	if len(members) == 0 {
+		return true
+	}
+
+	owner := ""
+	best := uint64(0)
+	for _, member := range members {
+		score := rendezvousScore(member, namespaceName)
+		if score > best || owner == "" {
+			best = score
+			owner = member
+		}
+	}
+	return owner == c.sharding.memberID
+}
+
+// rendezvousScore hashes a member/namespace pair
+func rendezvousScore(member string, namespaceName string) uint64 {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(member))
+	hasher.Write([]byte{'/'})
+	hasher.Write([]byte(namespaceName))
+	return hasher.Sum64()
+}
+
+// shardState is the sharding identity of this replica
+type shardState struct {
+	config   *ShardingConfig
+	memberID string
+}
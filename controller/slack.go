@@ -0,0 +1,16 @@
+package controller
+
+// SlackConfig enables the Slack slash command interface. The signing secret
+// comes from the Slack application configuration
+type SlackConfig struct {
+	SigningSecret string `yaml:"signingSecret"`
+}
+
+// SlackSigningSecret returns the secret Slack requests are checked against,
+// empty when the Slack integration is not configured
+func (c *Controller) SlackSigningSecret() string {
+	if c.slack == nil {
+		return ""
+	}
+	return c.slack.SigningSecret
+}
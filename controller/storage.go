@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// StorageConfig bounds the storage a caller can request for an instance
+type StorageConfig struct {
+	DefaultSize    string   `yaml:"defaultSize"`
+	MinSize        string   `yaml:"minSize"`
+	MaxSize        string   `yaml:"maxSize"`
+	DefaultClass   string   `yaml:"defaultClass"`
+	AllowedClasses []string `yaml:"allowedClasses"`
+}
+
+// resolveStorage validates the requested storage size and class against the
+// resource bounds and returns them with defaults applied
+func (c *Controller) resolveStorage(res Resource, options CreateOptions) (string, string, error) {
+	if res.Storage == nil {
+		if options.StorageSize != "" || options.StorageClass != "" {
+			return "", "", fmt.Errorf("resource %s does not support storage parameters", res.Name)
+		}
+		return "", "", nil
+	}
+
+	size := options.StorageSize
+	if size == "" {
+		size = res.Storage.DefaultSize
+	}
+	if size != "" {
+		quantity, err := resource.ParseQuantity(size)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid storage size %s: %v", size, err)
+		}
+		if res.Storage.MinSize != "" {
+			min, err := resource.ParseQuantity(res.Storage.MinSize)
+			if err != nil {
+				return "", "", err
+			}
+			if quantity.Cmp(min) < 0 {
+				return "", "", fmt.Errorf("storage size %s is below the minimum %s", size, res.Storage.MinSize)
+			}
+		}
+		if res.Storage.MaxSize != "" {
+			max, err := resource.ParseQuantity(res.Storage.MaxSize)
+			if err != nil {
+				return "", "", err
+			}
+			if quantity.Cmp(max) > 0 {
+				return "", "", fmt.Errorf("storage size %s is above the maximum %s", size, res.Storage.MaxSize)
+			}
+		}
+	}
+
+	class := options.StorageClass
+	if class == "" {
+		class = res.Storage.DefaultClass
+	}
+	if class != "" && len(res.Storage.AllowedClasses) > 0 {
+		allowed := false
+		for _, allowedClass := range res.Storage.AllowedClasses {
+			if class == allowedClass {
+				allowed = true
+			}
+		}
+		if allowed != true {
+			return "", "", fmt.Errorf("storage class %s is not allowed for resource %s", class, res.Name)
+		}
+	}
+
+	return size, class, nil
+}
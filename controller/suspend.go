@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/geobeau/k8s-ephemeral-resources/clock"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SuspendConfig scales the instances of a resource to zero outside of the
+// working hours and restores them in the morning, to save compute on dev
+// environments nobody is using at night or during the weekend
+type SuspendConfig struct {
+	WorkingHours []Window `yaml:"workingHours"`
+}
+
+// noSuspendAnnotation lets an owner opt an instance out of the suspension,
+// for the long-running test that really must survive the night
+const noSuspendAnnotation = "k8s-ephemeral-resources/no-suspend"
+
+// SuspendLoop wakes up every @delay to suspend or resume the instances of the
+// resources with a suspend policy
+func (c *Controller) SuspendLoop(delay time.Duration) {
+	for {
+		for _, resource := range c.Resources {
+			if resource.Suspend == nil {
+				continue
+			}
+			listOptions := metav1.ListOptions{LabelSelector: "k8sEphemResourceName=" + resource.Name}
+			list, err := c.kubeClient.CoreV1().Namespaces().List(listOptions)
+			if err != nil {
+				log.Println("Error:", err)
+				continue
+			}
+			for _, namespace := range list.Items {
+				if c.ownsInstance(namespace.Name) != true {
+					continue
+				}
+				c.applySuspendPolicy(resource, &namespace)
+			}
+		}
+		time.Sleep(delay)
+	}
+}
+
+// applySuspendPolicy suspends or resumes one instance depending on the
+// working hours of its resource
+func (c *Controller) applySuspendPolicy(resource Resource, namespace *apiv1.Namespace) {
+	if namespace.Annotations[noSuspendAnnotation] == "true" {
+		return
+	}
+	// An instance already retained by the retention config is managed there
+	if _, retained := namespace.Labels["k8sEphemResourceScaledDownAt"]; retained {
+		return
+	}
+
+	_, suspended := namespace.Labels["k8sEphemResourceSuspendedAt"]
+	working := insideWorkingHours(resource.Suspend, clock.Now())
+
+	if working != true && suspended != true {
+		err := c.suspendInstance(namespace)
+		if err != nil {
+			log.Println("Error:", err)
+		}
+	}
+	if working && suspended {
+		err := c.resumeInstance(namespace)
+		if err != nil {
+			log.Println("Error:", err)
+		}
+	}
+}
+
+// insideWorkingHours returns true when @now falls inside one of the working
+// hour windows
+func insideWorkingHours(config *SuspendConfig, now time.Time) bool {
+	if len(config.WorkingHours) == 0 {
+		return true
+	}
+	for _, window := range config.WorkingHours {
+		inside, err := window.contains(now)
+		if err != nil {
+			log.Println("Error:", err)
+			return true
+		}
+		if inside {
+			return true
+		}
+	}
+	return false
+}
+
+// suspendInstance scales every deployment of an instance to zero, keeping the
+// previous replica count for the morning
+func (c *Controller) suspendInstance(namespace *apiv1.Namespace) error {
+	log.Printf("Suspending %s outside of working hours", namespace.Name)
+	deployments, err := c.kubeClient.AppsV1beta2().Deployments(namespace.Name).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	zero := int32(0)
+	for _, deployment := range deployments.Items {
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+		if deployment.Annotations == nil {
+			deployment.Annotations = map[string]string{}
+		}
+		deployment.Annotations["k8s-ephemeral-resources/original-replicas"] = strconv.Itoa(int(replicas))
+		deployment.Spec.Replicas = &zero
+		_, err = c.kubeClient.AppsV1beta2().Deployments(namespace.Name).Update(&deployment)
+		if err != nil {
+			return err
+		}
+	}
+
+	namespace.Labels["k8sEphemResourceSuspendedAt"] = strconv.FormatInt(clock.Now().Unix(), 10)
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	return err
+}
+
+// resumeInstance scales a suspended instance back up, pushing its expiration
+// forward by the suspended duration so the owner does not pay TTL for the
+// night
+func (c *Controller) resumeInstance(namespace *apiv1.Namespace) error {
+	log.Printf("Resuming %s for working hours", namespace.Name)
+	deployments, err := c.kubeClient.AppsV1beta2().Deployments(namespace.Name).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, deployment := range deployments.Items {
+		replicas := int32(1)
+		original, err := strconv.Atoi(deployment.Annotations["k8s-ephemeral-resources/original-replicas"])
+		if err == nil && original > 0 {
+			replicas = int32(original)
+		}
+		deployment.Spec.Replicas = &replicas
+		delete(deployment.Annotations, "k8s-ephemeral-resources/original-replicas")
+		_, err = c.kubeClient.AppsV1beta2().Deployments(namespace.Name).Update(&deployment)
+		if err != nil {
+			return err
+		}
+	}
+
+	suspendedAtEpoch, err := strconv.ParseInt(namespace.Labels["k8sEphemResourceSuspendedAt"], 10, 64)
+	if err == nil {
+		suspendedFor := clock.Now().Sub(time.Unix(suspendedAtEpoch, 0))
+		expirationEpoch, err := strconv.ParseInt(namespace.Labels["k8sEphemResourceExpirationDate"], 10, 64)
+		if err == nil {
+			extended := time.Unix(expirationEpoch, 0).Add(suspendedFor)
+			namespace.Labels["k8sEphemResourceExpirationDate"] = strconv.FormatInt(extended.Unix(), 10)
+		}
+	}
+	delete(namespace.Labels, "k8sEphemResourceSuspendedAt")
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	c.nsCache.bust()
+	return err
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// tagLabelPrefix is prepended to tags to keep them apart from the
+// state labels of an instance
+const tagLabelPrefix = "k8sEphemTag-"
+
+var tagPattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._-]{0,48}[a-zA-Z0-9])?$`)
+
+// TaggedInstance is an instance matched by a tag search, across resources
+type TaggedInstance struct {
+	Namespace string
+	Resource  string
+	Owner     string
+}
+
+// TagInstance adds a free form tag on an instance so orchestration scripts
+// can group instances across resources
+func (c *Controller) TagInstance(name string, instanceID string, tag string) error {
+	if tagPattern.MatchString(tag) != true {
+		return fmt.Errorf("invalid tag %s", tag)
+	}
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+	_, tagged := namespace.Labels[tagLabelPrefix+tag]
+	if tagged {
+		return nil
+	}
+
+	log.Printf("Tagging %s with %s", instanceID, tag)
+	namespace.Labels[tagLabelPrefix+tag] = "true"
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	if err != nil {
+		return err
+	}
+	c.nsCache.bust()
+	return nil
+}
+
+// UntagInstance removes a tag from an instance
+func (c *Controller) UntagInstance(name string, instanceID string, tag string) error {
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if namespace.Labels["k8sEphemResourceName"] != name {
+		return fmt.Errorf("%s is not an instance of %s", instanceID, name)
+	}
+	_, tagged := namespace.Labels[tagLabelPrefix+tag]
+	if tagged != true {
+		return nil
+	}
+
+	log.Printf("Untagging %s from %s", instanceID, tag)
+	delete(namespace.Labels, tagLabelPrefix+tag)
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	if err != nil {
+		return err
+	}
+	c.nsCache.bust()
+	return nil
+}
+
+// InstancesByTag returns the instances carrying a tag, whatever their
+// resource, for coordinated reporting and bulk teardown
+func (c *Controller) InstancesByTag(tag string) ([]TaggedInstance, error) {
+	if tagPattern.MatchString(tag) != true {
+		return nil, fmt.Errorf("invalid tag %s", tag)
+	}
+	list, err := c.cachedInstances(tagLabelPrefix + tag + "=true")
+	if err != nil {
+		return nil, err
+	}
+
+	instances := []TaggedInstance{}
+	for _, namespace := range list.Items {
+		instances = append(instances, TaggedInstance{
+			Namespace: namespace.Name,
+			Resource: namespace.Labels["k8sEphemResourceName"],
+			Owner: namespace.Labels["k8sEphemResourceOwner"],
+		})
+	}
+	return instances, nil
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Tenant isolates a team using the manager. Instances created by a tenant
+// carry its name, use its suffix and are counted against its quotas
+type Tenant struct {
+	Name             string   `yaml:"name"`
+	Identities       []string `yaml:"identities"`
+	Suffix           string   `yaml:"suffix"`
+	MaxInstances     int      `yaml:"maxInstances"`
+	AllowedResources []string `yaml:"allowedResources"`
+}
+
+// TenantForIdentity maps an api caller identity to its tenant. When no
+// tenant is configured every caller shares the default scope
+func (c *Controller) TenantForIdentity(identity string) (Tenant, error) {
+	if len(c.tenants) == 0 {
+		return Tenant{}, nil
+	}
+	for _, tenant := range c.tenants {
+		for _, tenantIdentity := range tenant.Identities {
+			if tenantIdentity == identity {
+				return tenant, nil
+			}
+		}
+	}
+	return Tenant{}, fmt.Errorf("identity %s is not mapped to any tenant", identity)
+}
+
+// checkTenant verifies that the tenant can create an instance of a resource
+func (c *Controller) checkTenant(tenant Tenant, resource Resource) error {
+	if tenant.Name == "" {
+		return nil
+	}
+
+	if len(tenant.AllowedResources) > 0 {
+		allowed := false
+		for _, name := range tenant.AllowedResources {
+			if name == resource.Name {
+				allowed = true
+			}
+		}
+		if allowed != true {
+			return fmt.Errorf("tenant %s is not allowed to create %s instances", tenant.Name, resource.Name)
+		}
+	}
+
+	if tenant.MaxInstances > 0 {
+		listOptions := metav1.ListOptions{LabelSelector: "k8sEphemResourceTenant=" + tenant.Name}
+		list, err := c.kubeClient.CoreV1().Namespaces().List(listOptions)
+		if err != nil {
+			return err
+		}
+		if len(list.Items) >= tenant.MaxInstances {
+			return fmt.Errorf("tenant %s reached its quota of %d instances", tenant.Name, tenant.MaxInstances)
+		}
+	}
+
+	return nil
+}
+
+// tenantPrefix returns the namespace prefix of the tenant, defaulting to the
+// global one
+func (c *Controller) tenantPrefix(tenant Tenant) string {
+	if tenant.Suffix != "" {
+		return tenant.Suffix
+	}
+	return c.prefix
+}
@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TerraformBackend provisions an instance by running terraform inside a
+// kubernetes job instead of raw workloads. Each instance gets its own
+// workspace so states do not collide, and the backend config values are
+// templated with the instance so the state key can embed the namespace.
+// The image must contain terraform and kubectl
+type TerraformBackend struct {
+	Image          string            `yaml:"image"`
+	Source         string            `yaml:"source"`
+	Backend        map[string]string `yaml:"backend"`
+	ServiceAccount string            `yaml:"serviceAccount"`
+}
+
+// provisionTerraform creates the namespace of the instance and a job running
+// terraform apply in it. The job publishes the terraform outputs in the
+// terraform-outputs configmap so they can be served as connection details
+func (c *Controller) provisionTerraform(resource Resource, instance Instance, labels map[string]string) error {
+	identifier := instance.Namespace
+	namespace := &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: identifier, Labels: labels}}
+
+	log.Println("Creating namespace: ", identifier)
+
+	_, err := c.kubeClient.CoreV1().Namespaces().Create(namespace)
+	if err != nil {
+		return err
+	}
+
+	script, err := c.terraformScript(resource, instance, false)
+	if err != nil {
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
+	}
+
+	log.Println("Creating terraform apply job in: ", identifier)
+	_, err = c.kubeClient.BatchV1().Jobs(identifier).Create(terraformJob(resource, "terraform-apply", script))
+	if err != nil {
+		log.Println("Error while create resource, removing namespace")
+		c.kubeClient.CoreV1().Namespaces().Delete(identifier, nil)
+		return err
+	}
+	return nil
+}
+
+// destroyTerraform drives the teardown of a terraform backed instance. The
+// destroy job is created on the first call and the instance is only reported
+// as destroyed once the job succeeded, so the namespace removal waits for
+// terraform destroy to complete
+func (c *Controller) destroyTerraform(resource Resource, namespaceName string) (bool, error) {
+	job, err := c.kubeClient.BatchV1().Jobs(namespaceName).Get("terraform-destroy", metav1.GetOptions{})
+	if err == nil {
+		return job.Status.Succeeded > 0, nil
+	}
+
+	instance := Instance{Namespace: namespaceName}
+	script, err := c.terraformScript(resource, instance, true)
+	if err != nil {
+		return false, err
+	}
+
+	log.Println("Creating terraform destroy job in: ", namespaceName)
+	_, err = c.kubeClient.BatchV1().Jobs(namespaceName).Create(terraformJob(resource, "terraform-destroy", script))
+	return false, err
+}
+
+// terraformScript builds the shell script run by the terraform jobs. Init
+// and workspace selection are shared between apply and destroy
+func (c *Controller) terraformScript(resource Resource, instance Instance, destroy bool) (string, error) {
+	backend := resource.Terraform
+
+	lines := []string{"set -e"}
+	initArgs := []string{"terraform init -input=false"}
+	keys := []string{}
+	for key := range backend.Backend {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, err := renderTemplate(instance.Namespace, backend.Backend[key], instance)
+		if err != nil {
+			return "", err
+		}
+		initArgs = append(initArgs, fmt.Sprintf("-backend-config=%s=%s", key, value))
+	}
+	if backend.Source != "" {
+		initArgs = append(initArgs, "-from-module=" + backend.Source)
+	}
+	lines = append(lines, strings.Join(initArgs, " "))
+	lines = append(lines, fmt.Sprintf("terraform workspace select %s || terraform workspace new %s", instance.Namespace, instance.Namespace))
+	if destroy {
+		lines = append(lines, "terraform destroy -input=false -auto-approve")
+		lines = append(lines, fmt.Sprintf("terraform workspace select default && terraform workspace delete %s", instance.Namespace))
+	} else {
+		lines = append(lines, "terraform apply -input=false -auto-approve")
+		lines = append(lines, "terraform output -json > /tmp/outputs.json")
+		lines = append(lines, fmt.Sprintf("kubectl create configmap terraform-outputs -n %s --from-file=outputs.json=/tmp/outputs.json --dry-run -o yaml | kubectl apply -f -", instance.Namespace))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// terraformJob builds the job running a terraform script
+func terraformJob(resource Resource, name string, script string) *batchv1.Job {
+	backoffLimit := int32(3)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: apiv1.PodTemplateSpec{
+				Spec: apiv1.PodSpec{
+					RestartPolicy:      apiv1.RestartPolicyNever,
+					ServiceAccountName: resource.Terraform.ServiceAccount,
+					Containers: []apiv1.Container{
+						{
+							Name:    "terraform",
+							Image:   resource.Terraform.Image,
+							Command: []string{"sh", "-c", script},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// TerraformOutputs returns the terraform outputs of an instance as its
+// connection details
+func (c *Controller) TerraformOutputs(name string, instanceID string) (map[string]interface{}, error) {
+	resource, ok := c.Resources[name]
+	if ok != true {
+		return nil, fmt.Errorf("Resource Not found")
+	}
+	if resource.Terraform == nil {
+		return nil, fmt.Errorf("resource %s is not terraform backed", name)
+	}
+
+	configMap, err := c.kubeClient.CoreV1().ConfigMaps(instanceID).Get("terraform-outputs", metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	rawOutputs := map[string]struct {
+		Value interface{} `json:"value"`
+	}{}
+	err = json.Unmarshal([]byte(configMap.Data["outputs.json"]), &rawOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := map[string]interface{}{}
+	for key, output := range rawOutputs {
+		outputs[key] = output.Value
+	}
+	return outputs, nil
+}
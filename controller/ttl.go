@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// resolveTTL returns the TTL of a new instance. Named tiers come from the
+// configuration, so policy changes are made centrally instead of clients
+// hardcoding durations. With the "pipeline" hint the caller passes its
+// estimated duration and a safety margin is added, so CI environments do not
+// outlive their pipeline by the full default TTL
+func (c *Controller) resolveTTL(resource Resource, options CreateOptions) (time.Duration, error) {
+	if options.Tier != "" {
+		tier, ok := c.ttlTiers[options.Tier]
+		if ok != true {
+			return 0, fmt.Errorf("unknown TTL tier: %s", options.Tier)
+		}
+		return time.Duration(tier), nil
+	}
+	if options.TTLFrom == "" {
+		return time.Duration(resource.DurationDefault), nil
+	}
+	if options.TTLFrom != "pipeline" {
+		return 0, fmt.Errorf("unknown ttlFrom hint: %s", options.TTLFrom)
+	}
+	if options.EstimatedDuration == "" {
+		return 0, errors.New("ttlFrom pipeline requires an estimatedDuration")
+	}
+
+	estimated, err := time.ParseDuration(options.EstimatedDuration)
+	if err != nil {
+		return 0, fmt.Errorf("invalid estimatedDuration %s: %v", options.EstimatedDuration, err)
+	}
+
+	margin := c.ttlSafetyMargin
+	if margin == 0 {
+		margin = 10 * time.Minute
+	}
+	return estimated + margin, nil
+}
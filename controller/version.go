@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InstanceSummary is the list/status view of an instance
+type InstanceSummary struct {
+	Namespace       string
+	Owner           string
+	ExpirationDate  string
+	TemplateVersion string
+	Outdated        bool
+}
+
+// templateVersion returns a short hash of a resource definition, stamped on
+// every instance so the template used at creation stays identifiable
+func templateVersion(resource Resource) string {
+	definition, err := json.Marshal(resource)
+	if err != nil {
+		return "unknown"
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(definition))[:12]
+}
+
+// ListInstances returns the instances of a resource with their template
+// version
+func (c *Controller) ListInstances(name string) ([]InstanceSummary, error) {
+	resource, ok := c.Resources[name]
+	if ok != true {
+		return nil, fmt.Errorf("Resource Not found")
+	}
+	currentVersion := templateVersion(resource)
+
+	instances := []InstanceSummary{}
+	list, err := c.cachedInstances("k8sEphemResourceName=" + name)
+	if err != nil {
+		return nil, err
+	}
+	for _, namespace := range list.Items {
+		version := namespace.Labels["k8sEphemResourceTemplateVersion"]
+		summary := InstanceSummary{
+			Namespace: namespace.Name,
+			Owner: namespace.Labels["k8sEphemResourceOwner"],
+			TemplateVersion: version,
+			Outdated: version != currentVersion,
+		}
+		expiration, err := expirationDate(&namespace)
+		if err == nil {
+			summary.ExpirationDate = expiration.Format(time.RFC3339)
+		}
+		instances = append(instances, summary)
+	}
+	return instances, nil
+}
+
+// ListInstancesPage returns one page of the instances of a resource, with
+// the opaque kubernetes continue token of the next page. Pages are sorted by
+// creation time, so large installs keep responses bounded and stable
+func (c *Controller) ListInstancesPage(name string, limit int64, continueToken string) ([]InstanceSummary, string, error) {
+	resource, ok := c.Resources[name]
+	if ok != true {
+		return nil, "", fmt.Errorf("Resource Not found")
+	}
+	currentVersion := templateVersion(resource)
+
+	list, err := c.kubeClient.CoreV1().Namespaces().List(metav1.ListOptions{
+		LabelSelector: "k8sEphemResourceName=" + name,
+		Limit:         limit,
+		Continue:      continueToken,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		left, right := list.Items[i].CreationTimestamp, list.Items[j].CreationTimestamp
+		if left.Equal(&right) {
+			return list.Items[i].Name < list.Items[j].Name
+		}
+		return left.Before(&right)
+	})
+
+	instances := []InstanceSummary{}
+	for _, namespace := range list.Items {
+		version := namespace.Labels["k8sEphemResourceTemplateVersion"]
+		summary := InstanceSummary{
+			Namespace: namespace.Name,
+			Owner: namespace.Labels["k8sEphemResourceOwner"],
+			TemplateVersion: version,
+			Outdated: version != currentVersion,
+		}
+		expiration, err := expirationDate(&namespace)
+		if err == nil {
+			summary.ExpirationDate = expiration.Format(time.RFC3339)
+		}
+		instances = append(instances, summary)
+	}
+	return instances, list.Continue, nil
+}
+
+// expirationDate parses the expiration label of an instance namespace
+func expirationDate(namespace *apiv1.Namespace) (time.Time, error) {
+	expirationStr, ok := namespace.Labels["k8sEphemResourceExpirationDate"]
+	if ok != true {
+		return time.Time{}, fmt.Errorf("%s has no expiration date", namespace.Name)
+	}
+	expirationEpoch, err := strconv.ParseInt(expirationStr, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(expirationEpoch, 0), nil
+}
+
+// OutdatedInstances returns the instances created from an older version of
+// the resource definition, so they can be recycled after a template fix
+func (c *Controller) OutdatedInstances(name string) ([]InstanceSummary, error) {
+	instances, err := c.ListInstances(name)
+	if err != nil {
+		return nil, err
+	}
+	outdated := []InstanceSummary{}
+	for _, instance := range instances {
+		if instance.Outdated {
+			outdated = append(outdated, instance)
+		}
+	}
+	return outdated, nil
+}
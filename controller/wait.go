@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WaitResult is the outcome of waiting on an instance
+type WaitResult struct {
+	State    string            `json:"state"`
+	Services []ServiceEndpoint `json:"services,omitempty"`
+}
+
+// ServiceEndpoint is how a ready instance can be reached
+type ServiceEndpoint struct {
+	Name      string  `json:"name"`
+	ClusterIP string  `json:"clusterIP"`
+	Ports     []int32 `json:"ports"`
+}
+
+// WaitForInstance blocks until an instance reaches READY or FAILED, for CI
+// scripts that otherwise sleep-and-poll with arbitrary delays. READY comes
+// with the connection details of the instance
+func (c *Controller) WaitForInstance(name string, instanceID string, timeout time.Duration) (WaitResult, error) {
+	resource, ok := c.Resources[name]
+	if ok != true {
+		return WaitResult{}, fmt.Errorf("Resource Not found")
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		namespace, err := c.kubeClient.CoreV1().Namespaces().Get(instanceID, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return WaitResult{State: "FAILED"}, nil
+		}
+		if err != nil {
+			return WaitResult{}, err
+		}
+		if namespace.Labels["k8sEphemResourceName"] != name {
+			return WaitResult{}, fmt.Errorf("%s is not an instance of %s", instanceID, name)
+		}
+		if namespace.DeletionTimestamp != nil {
+			return WaitResult{State: "FAILED"}, nil
+		}
+
+		ready, failed, err := c.instanceReady(resource, instanceID)
+		if err != nil {
+			return WaitResult{}, err
+		}
+		if failed {
+			return WaitResult{State: "FAILED"}, nil
+		}
+		if ready {
+			services, err := c.instanceEndpoints(instanceID)
+			if err != nil {
+				return WaitResult{}, err
+			}
+			return WaitResult{State: "READY", Services: services}, nil
+		}
+
+		if time.Now().After(deadline) {
+			return WaitResult{State: "TIMEOUT"}, nil
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// instanceReady tells whether the workloads of an instance came up, and
+// whether they failed for good
+func (c *Controller) instanceReady(resource Resource, instanceID string) (bool, bool, error) {
+	if resource.Claim != nil {
+		ready, err := c.ClaimReady(resource.Name, instanceID)
+		return ready, false, err
+	}
+	if resource.Sandbox != nil || resource.Manifest != nil {
+		return true, false, nil
+	}
+	if resource.Job != nil || resource.Terraform != nil {
+		jobs, err := c.kubeClient.BatchV1().Jobs(instanceID).List(metav1.ListOptions{})
+		if err != nil {
+			return false, false, err
+		}
+		if len(jobs.Items) == 0 {
+			return false, false, nil
+		}
+		// The job existing only means provisioning started: READY needs a
+		// successful completion, and a job past its backoff limit will never
+		// complete
+		for _, job := range jobs.Items {
+			if job.Status.Succeeded > 0 {
+				continue
+			}
+			backoffLimit := int32(6)
+			if job.Spec.BackoffLimit != nil {
+				backoffLimit = *job.Spec.BackoffLimit
+			}
+			if job.Status.Failed > backoffLimit {
+				return false, true, nil
+			}
+			return false, false, nil
+		}
+		return true, false, nil
+	}
+
+	deployments, err := c.kubeClient.AppsV1beta2().Deployments(instanceID).List(metav1.ListOptions{})
+	if err != nil {
+		return false, false, err
+	}
+	if len(deployments.Items) == 0 {
+		return false, false, nil
+	}
+	for _, deployment := range deployments.Items {
+		if deployment.Status.AvailableReplicas < 1 {
+			return false, false, nil
+		}
+	}
+	return true, false, nil
+}
+
+// instanceEndpoints lists the services of an instance
+func (c *Controller) instanceEndpoints(instanceID string) ([]ServiceEndpoint, error) {
+	services, err := c.kubeClient.CoreV1().Services(instanceID).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	endpoints := []ServiceEndpoint{}
+	for _, service := range services.Items {
+		endpoint := ServiceEndpoint{Name: service.Name, ClusterIP: service.Spec.ClusterIP}
+		for _, port := range service.Spec.Ports {
+			endpoint.Ports = append(endpoint.Ports, port.Port)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
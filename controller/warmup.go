@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WarmUpConfig makes the creation wait for the workloads to become available
+// and retry on a fresh namespace when a rollout never completes, since
+// transient node pressure often breaks first attempts
+type WarmUpConfig struct {
+	Timeout    time.Duration `yaml:"timeout"`
+	MaxRetries int           `yaml:"maxRetries"`
+}
+
+// warmUpAttempts returns how many provisioning attempts a resource allows
+func warmUpAttempts(resource Resource) int {
+	if resource.WarmUp == nil {
+		return 1
+	}
+	return 1 + resource.WarmUp.MaxRetries
+}
+
+// waitForRollout polls the deployments of a namespace until they are all
+// available or the timeout is reached
+func (c *Controller) waitForRollout(namespaceName string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		deployments, err := c.kubeClient.AppsV1beta2().Deployments(namespaceName).List(metav1.ListOptions{})
+		if err != nil {
+			log.Println("Error:", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		available := len(deployments.Items) > 0
+		for _, deployment := range deployments.Items {
+			if deployment.Status.AvailableReplicas < 1 {
+				available = false
+			}
+		}
+		if available {
+			return true
+		}
+		time.Sleep(5 * time.Second)
+	}
+	log.Printf("Rollout of %s did not complete within %s", namespaceName, timeout)
+	return false
+}
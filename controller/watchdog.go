@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"log"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// stuckTerminatingThreshold is how long a namespace can stay in Terminating
+// before being reported as stuck
+const stuckTerminatingThreshold = 10 * time.Minute
+
+// InstanceState describes the deletion state of an instance namespace
+type InstanceState struct {
+	Namespace string
+	Resource  string
+	Phase     string
+	Since     string
+}
+
+// StuckInstances returns the instance namespaces stuck in Terminating beyond
+// the threshold, usually because of a lingering finalizer
+func (c *Controller) StuckInstances() ([]InstanceState, error) {
+	stuck := []InstanceState{}
+	list, err := c.cachedInstances("k8sEphemResourceName")
+	if err != nil {
+		return nil, err
+	}
+	for _, namespace := range list.Items {
+		if isStuckTerminating(&namespace) {
+			stuck = append(stuck, InstanceState{
+				Namespace: namespace.Name,
+				Resource: namespace.Labels["k8sEphemResourceName"],
+				Phase: string(namespace.Status.Phase),
+				Since: namespace.DeletionTimestamp.Format(time.RFC3339),
+			})
+		}
+	}
+	return stuck, nil
+}
+
+// isStuckTerminating reports whether a namespace has been Terminating beyond
+// the threshold
+func isStuckTerminating(namespace *apiv1.Namespace) bool {
+	if namespace.Status.Phase != apiv1.NamespaceTerminating {
+		return false
+	}
+	return namespace.DeletionTimestamp != nil && time.Since(namespace.DeletionTimestamp.Time) > stuckTerminatingThreshold
+}
+
+// reportStuckInstance surfaces a stuck namespace and, when remediation is
+// enabled, drops its finalizers so the deletion can complete
+func (c *Controller) reportStuckInstance(namespace *apiv1.Namespace) {
+	log.Printf("Alert: namespace %s is stuck in Terminating since %s", namespace.Name, namespace.DeletionTimestamp.Format(time.RFC3339))
+	if c.remediateStuck != true {
+		return
+	}
+
+	log.Printf("Dropping finalizers of %s", namespace.Name)
+	namespace.Spec.Finalizers = []apiv1.FinalizerName{}
+	_, err := c.kubeClient.CoreV1().Namespaces().Finalize(namespace)
+	if err != nil {
+		log.Println("Error:", err)
+	}
+}
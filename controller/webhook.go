@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WebhookConfig turns VCS pull request events into preview environments
+type WebhookConfig struct {
+	Secret      string             `yaml:"secret"`
+	Mappings    []WebhookMapping   `yaml:"mappings"`
+	CommentBack *CommentBackConfig `yaml:"commentBack"`
+}
+
+// WebhookMapping maps a repository to the resource its pull requests get
+type WebhookMapping struct {
+	Repository string `yaml:"repository"`
+	Resource   string `yaml:"resource"`
+}
+
+var tagSanitizer = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// prTag returns the tag tying an instance to a pull request, so the close
+// event can find it back
+func prTag(repository string, number int) string {
+	return fmt.Sprintf("pr-%s-%d", tagSanitizer.ReplaceAllString(repository, "-"), number)
+}
+
+// webhookMapping returns the resource mapped to a repository
+func (c *Controller) webhookMapping(repository string) (WebhookMapping, error) {
+	if c.webhooks == nil {
+		return WebhookMapping{}, fmt.Errorf("webhooks are not configured")
+	}
+	for _, mapping := range c.webhooks.Mappings {
+		if mapping.Repository == repository {
+			return mapping, nil
+		}
+	}
+	return WebhookMapping{}, fmt.Errorf("no resource mapped to repository %s", repository)
+}
+
+// WebhookSecret returns the shared secret webhook deliveries are checked
+// against, empty when webhooks are not configured
+func (c *Controller) WebhookSecret() string {
+	if c.webhooks == nil {
+		return ""
+	}
+	return c.webhooks.Secret
+}
+
+// OpenPreview creates a preview instance for a pull request, records the
+// branch and commit it was built from and comments its coordinates back on
+// the pull request
+func (c *Controller) OpenPreview(provider string, repository string, number int, branch string, sha string, author string) (Instance, error) {
+	mapping, err := c.webhookMapping(repository)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	log.Printf("Creating a preview of %s for %s#%d", mapping.Resource, repository, number)
+	instance, err := c.CreateNewInstance(mapping.Resource, CreateOptions{Owner: author})
+	if err != nil {
+		return Instance{}, err
+	}
+
+	err = c.TagInstance(mapping.Resource, instance.Namespace, prTag(repository, number))
+	if err != nil {
+		log.Println("Error:", err)
+	}
+
+	commentID := ""
+	if c.webhooks.CommentBack != nil {
+		commentID, err = c.postPreviewComment(provider, repository, number, instance)
+		if err != nil {
+			log.Println("Error:", err)
+		}
+	}
+	c.annotatePreview(instance.Namespace, repository, branch, sha, commentID)
+	return instance, nil
+}
+
+// annotatePreview records the pull request context on the namespace
+func (c *Controller) annotatePreview(namespaceName string, repository string, branch string, sha string, commentID string) {
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(namespaceName, metav1.GetOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	if namespace.Annotations == nil {
+		namespace.Annotations = map[string]string{}
+	}
+	namespace.Annotations["k8s-ephemeral-resources/repository"] = repository
+	namespace.Annotations["k8s-ephemeral-resources/branch"] = branch
+	namespace.Annotations["k8s-ephemeral-resources/sha"] = sha
+	if commentID != "" {
+		namespace.Annotations["k8s-ephemeral-resources/comment-id"] = commentID
+	}
+	_, err = c.kubeClient.CoreV1().Namespaces().Update(namespace)
+	if err != nil {
+		log.Println("Error:", err)
+	}
+}
+
+// ClosePreview deletes the preview instances of a pull request and the
+// comments pointing at them
+func (c *Controller) ClosePreview(provider string, repository string, number int) error {
+	mapping, err := c.webhookMapping(repository)
+	if err != nil {
+		return err
+	}
+
+	instances, err := c.InstancesByTag(prTag(repository, number))
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		log.Printf("Deleting the preview %s of %s#%d", instance.Namespace, repository, number)
+		c.removePreviewComment(provider, repository, instance.Namespace)
+		_, err := c.MarkInstanceForDeletion(mapping.Resource, instance.Namespace)
+		if err != nil {
+			log.Println("Error:", err)
+		}
+	}
+	return nil
+}
+
+// removePreviewComment deletes the comment recorded on a preview namespace
+func (c *Controller) removePreviewComment(provider string, repository string, namespaceName string) {
+	if c.webhooks.CommentBack == nil {
+		return
+	}
+	namespace, err := c.kubeClient.CoreV1().Namespaces().Get(namespaceName, metav1.GetOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	commentID, ok := namespace.Annotations["k8s-ephemeral-resources/comment-id"]
+	if ok != true {
+		return
+	}
+	err = c.deletePreviewComment(provider, repository, commentID)
+	if err != nil {
+		log.Println("Error:", err)
+	}
+}
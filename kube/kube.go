@@ -0,0 +1,80 @@
+package kube
+
+import (
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Options configures how the clients reach the cluster
+type Options struct {
+	// Kubeconfig is the path of the kubeconfig file, ignored when InCluster
+	Kubeconfig string
+	// InCluster uses the service account of the pod instead of a kubeconfig
+	InCluster bool
+	// Context selects a context of the kubeconfig, empty for the current one
+	Context string
+	// QPS and Burst tune the client-side rate limit, 0 keeps the defaults
+	QPS   float32
+	Burst int
+	// UserAgent identifies the client in the apiserver audit logs
+	UserAgent string
+}
+
+// NewClient returns a kubernetes client built from @opts
+func NewClient(opts Options) (*kubernetes.Clientset, error) {
+	config, err := restConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// NewClients returns a kubernetes client and a dynamic client built from
+// @opts, sharing the same configuration
+func NewClients(opts Options) (*kubernetes.Clientset, dynamic.Interface, error) {
+	config, err := restConfig(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return kubeClient, dynamicClient, nil
+}
+
+// restConfig builds the rest configuration from @opts
+func restConfig(opts Options) (*rest.Config, error) {
+	var config *rest.Config
+	var err error
+	switch {
+	case opts.InCluster:
+		config, err = rest.InClusterConfig()
+	case opts.Context != "":
+		config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: opts.Kubeconfig},
+			&clientcmd.ConfigOverrides{CurrentContext: opts.Context},
+		).ClientConfig()
+	default:
+		config, err = clientcmd.BuildConfigFromFlags("", opts.Kubeconfig)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.QPS > 0 {
+		config.QPS = opts.QPS
+	}
+	if opts.Burst > 0 {
+		config.Burst = opts.Burst
+	}
+	if opts.UserAgent != "" {
+		config.UserAgent = opts.UserAgent
+	}
+	return config, nil
+}
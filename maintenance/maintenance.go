@@ -0,0 +1,49 @@
+// Package maintenance holds the global kill-switch pausing every automatic
+// action: cleanup deletions in the controller and retaliation in the
+// watcher. During an apiserver upgrade watches get flaky and false
+// positives spike, better to sit still until a human resumes
+package maintenance
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	mutex  sync.Mutex
+	active bool
+	reason string
+	since  time.Time
+)
+
+// Enable pauses the automatic actions, recording why
+func Enable(why string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	active = true
+	reason = why
+	since = time.Now()
+}
+
+// Disable resumes the automatic actions
+func Disable() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	active = false
+	reason = ""
+	since = time.Time{}
+}
+
+// Active returns true while the maintenance mode is on
+func Active() bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return active
+}
+
+// Status returns the maintenance mode, its reason and when it started
+func Status() (bool, string, time.Time) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return active, reason, since
+}
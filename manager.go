@@ -6,82 +6,338 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/geobeau/k8s-ephemeral-resources/api"
 	"github.com/geobeau/k8s-ephemeral-resources/controller"
+	"github.com/geobeau/k8s-ephemeral-resources/kube"
+	"github.com/geobeau/k8s-ephemeral-resources/metrics"
+	"github.com/geobeau/k8s-ephemeral-resources/tracing"
+	"github.com/geobeau/k8s-ephemeral-resources/watcher"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 
-	yaml "gopkg.in/yaml.v3"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 	"github.com/gorilla/mux"
 )
 
 
 func main() {
-	app := kingpin.New("k8s-ephemeral-resources", "A controller to manage and deploy short lived resources")
+	app := kingpin.New("k8s-ephemeral-resources", "A manager for ephemeral resources and stateful clusters")
 	app.HelpFlag.Short('h')
 
-	confPath := app.Flag("conf", "Configuration to be used by the manager").Short('c').Default("./config.yaml").String()
-
-	suffix := app.Flag("suffix", "Suffix for the namespace of the created instances").Default("ephem-").String()
-	
 	kubeconfig := app.Flag("kubeconfig", "(optional) absolute path to a kubeconfig file").Default(filepath.Join(os.Getenv("HOME"), ".kube", "config")).String()
 	runInsideKube := app.Flag("runInsideKube", "if true will setup").Default("false").Bool()
+	kubeQPS := app.Flag("kube-qps", "Client-side rate limit of the kube client, 0 for the default").Default("0").Float()
+	kubeBurst := app.Flag("kube-burst", "Client-side burst of the kube client, 0 for the default").Default("0").Int()
 
 	httpListenPort := app.Flag("httpListenPort", "Port on which the http server should bind on").Default("8080").String()
+	otlpEndpoint := app.Flag("otlpEndpoint", "OTLP http endpoint traces should be exported to, disabled when empty").Default("").String()
 
-	cleanUpInterval := app.Flag("interval", "Interval between searching for resources to clean-up").Short('d').Default("1m").Duration()
-	app.Parse(os.Args[1:])
+	serve := app.Command("serve", "Serve the ephemeral resources api")
+	confPath := serve.Flag("conf", "Configuration to be used by the manager").Short('c').Default("./config.yaml").String()
+	namespacePrefix := serve.Flag("namespace-prefix", "Prefix for the namespaces of the created instances").Default("ephem-").String()
+	suffix := serve.Flag("suffix", "Deprecated, use --namespace-prefix").Default("").String()
+	cleanUpInterval := serve.Flag("interval", "Interval between searching for resources to clean-up").Short('d').Default("1m").Duration()
+	deleteGraceWindow := serve.Flag("deleteGraceWindow", "Undo window before a deleted instance is actually removed").Default("10m").Duration()
+	remediateStuck := serve.Flag("remediateStuckNamespaces", "Drop the finalizers of namespaces stuck in Terminating").Default("false").Bool()
 
-	// Parsing Configuration
-	config := controller.Config{}
+	convert := app.Command("convert", "Convert a docker-compose file into a draft resource definition")
+	composePath := convert.Flag("compose", "Path of the docker-compose file to convert").Short('f').Default("./docker-compose.yaml").String()
+	resourceName := convert.Flag("name", "Name of the generated resource").Default("imported-stack").String()
 
-	log.Println("Reading configuration file:", *confPath)
-	data, err := ioutil.ReadFile(*confPath)
-	if err != nil {
-		log.Fatalf("error while reading %s: %v", *confPath, err)
+	watch := app.Command("watch", "Watch stateful clusters and retaliate against unhealthy pods")
+	namespacePattern := watch.Flag("namespacePattern", "Glob pattern of the namespaces to watch").Default("*").String()
+	gracePeriod := watch.Flag("gracePeriod", "Duration a cluster can stay unhealthy before retaliation").Default("10m").Duration()
+	maxKillsPerHour := watch.Flag("maxKillsPerHour", "Maximum kills per namespace in a rolling hour, 0 for unlimited").Default("0").Int()
+	cooldown := watch.Flag("cooldown", "Duration to wait after a kill before the next retaliation").Default("5m").Duration()
+	checkInterval := watch.Flag("interval", "Interval between two health checks").Short('d').Default("30s").Duration()
+	checkJitter := watch.Flag("jitter", "Maximum random jitter added between two evaluations").Default("0s").Duration()
+	retaliations := watch.Flag("retaliation", "Action chain per namespace pattern, like 'pattern=kill-pod,alert' (repeatable)").Strings()
+	excludeSelector := watch.Flag("exclude-selector", "Label selector of pods to never retaliate against").Default("").String()
+	excludeOwnerKinds := watch.Flag("exclude-owner-kind", "Owner kind whose pods are never retaliated against, like Job (repeatable)").Strings()
+	retaliateOn := watch.Flag("retaliate-on", "Cluster state to retaliate on, like DEGRADED or FAILED (repeatable)").Strings()
+	alertWebhook := watch.Flag("alert-webhook", "Webhook alerted when a cluster reaches half of its grace period").Default("").String()
+	maxKillsPerPod := watch.Flag("maxKillsPerPod", "Maximum kills of one pod in a rolling hour before stopping retaliation, 0 for unlimited").Default("0").Int()
+	persistKillHistory := watch.Flag("persist-kill-history", "Persist the kill history in a ConfigMap per watched namespace").Default("false").Bool()
+	watchContexts := watch.Flag("context", "Kubeconfig context to watch, one watcher per context (repeatable)").Strings()
+	maintenanceToken := watch.Flag("maintenance-token", "Bearer token required to toggle maintenance mode, toggling is rejected when unset").Default("").String()
+
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	if command == convert.FullCommand() {
+		runConvert(*composePath, *resourceName)
+		return
+	}
+
+	if *otlpEndpoint != "" {
+		tracing.Configure(*otlpEndpoint, "k8s-ephemeral-resources")
+	}
+
+	clientOpts := kube.Options{
+		Kubeconfig: *kubeconfig,
+		InCluster:  *runInsideKube,
+		QPS:        float32(*kubeQPS),
+		Burst:      *kubeBurst,
+		UserAgent:  "k8s-ephemeral-resources",
 	}
-	err = yaml.Unmarshal([]byte(data), &config)
+	kubeClient, dynamicClient, err := kube.NewClients(clientOpts)
 	if err != nil {
-		log.Fatalf("error while parsing yaml: %v", err)
+		log.Fatal("Cannot create the kube client driver ", err)
 	}
 
-	// Init kubernetes controller
-	var k8sConfig *rest.Config
-	if *runInsideKube {
-		k8sConfig, err = rest.InClusterConfig()
-	} else {
-		k8sConfig, err = clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	http.Handle("/metrics", metrics.Handler())
+
+	switch command {
+	case serve.FullCommand():
+		prefix := *namespacePrefix
+		if *suffix != "" {
+			log.Println("Warning: --suffix is deprecated, use --namespace-prefix")
+			prefix = *suffix
+		}
+		err := controller.ValidateNamespacePrefix(prefix)
+		if err != nil {
+			log.Fatal(err)
+		}
+		runServe(kubeClient, dynamicClient, *confPath, prefix, *cleanUpInterval, *deleteGraceWindow, *remediateStuck)
+	case watch.FullCommand():
+		runWatch(kubeClient, clientOpts, *watchContexts, *namespacePattern, *gracePeriod, *maxKillsPerHour, *cooldown, *checkInterval, *retaliations, *excludeSelector, *excludeOwnerKinds, *retaliateOn, *alertWebhook, *maxKillsPerPod, *persistKillHistory, *checkJitter, *maintenanceToken)
 	}
 
+	log.Println("Serving api on:", *httpListenPort)
+	log.Fatal(http.ListenAndServe(":" + *httpListenPort, nil))
+}
+
+// runServe starts the ephemeral resources api and its cleanup loop
+func runServe(kubeClient *kubernetes.Clientset, dynamicClient dynamic.Interface, confPath string, prefix string, cleanUpInterval time.Duration, deleteGraceWindow time.Duration, remediateStuck bool) {
+	// Parsing Configuration
+	log.Println("Reading configuration file:", confPath)
+	data, err := ioutil.ReadFile(confPath)
 	if err != nil {
-		log.Fatal("Cannot create the kube client driver ", err)
+		log.Fatalf("error while reading %s: %v", confPath, err)
 	}
-	kubeClient, err := kubernetes.NewForConfig(k8sConfig)
+	config, err := controller.LoadConfig(data)
 	if err != nil {
-		log.Fatal("Cannot create the kube client driver ", err)
+		log.Fatalf("invalid configuration %s: %v", confPath, err)
 	}
 
-	contrl := controller.NewControllerFromConfig(config, kubeClient, *suffix)
+	contrl := controller.NewControllerFromConfig(config, kubeClient, dynamicClient, prefix, deleteGraceWindow, remediateStuck)
+	go contrl.ScanPrefixCollisions()
+	go contrl.PublishCatalog()
 
 	r := mux.NewRouter()
+	r.HandleFunc("/catalog", func(w http.ResponseWriter, r *http.Request) {
+		api.GetCatalog(w, r, contrl)
+	}).Methods("GET")
+	r.HandleFunc("/instances", func(w http.ResponseWriter, r *http.Request) {
+		api.GetInstances(w, r, contrl)
+	}).Methods("GET")
 	r.HandleFunc("/resources/{resource}", func(w http.ResponseWriter, r *http.Request) {
 		api.GetResource(w, r, contrl)
 	}).Methods("GET")
 	r.HandleFunc("/resources/{resource}", func(w http.ResponseWriter, r *http.Request) {
 		api.CreateResource(w, r, contrl)
 	}).Methods("POST")
+	r.HandleFunc("/resources/{resource}/render", func(w http.ResponseWriter, r *http.Request) {
+		api.RenderResource(w, r, contrl)
+	}).Methods("POST")
 	r.HandleFunc("/resources/{resource}/{resourceId}", func(w http.ResponseWriter, r *http.Request) {
 		api.DeleteResource(w, r, contrl)
 	}).Methods("DELETE")
-	http.Handle("/", r)
+	r.HandleFunc("/resources/{resource}/{resourceId}/undelete", func(w http.ResponseWriter, r *http.Request) {
+		api.UndeleteResource(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/resources/{resource}/{resourceId}/resurrect", func(w http.ResponseWriter, r *http.Request) {
+		api.ResurrectResource(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/resources/{resource}/{resourceId}/heartbeat", func(w http.ResponseWriter, r *http.Request) {
+		api.HeartbeatResource(w, r, contrl)
+	}).Methods("PUT")
+	r.HandleFunc("/resources/{resource}/{resourceId}/status", func(w http.ResponseWriter, r *http.Request) {
+		api.GetResourceStatus(w, r, contrl)
+	}).Methods("GET")
+	r.HandleFunc("/resources/{resource}/{resourceId}/wait", func(w http.ResponseWriter, r *http.Request) {
+		api.WaitForResource(w, r, contrl)
+	}).Methods("GET")
+	r.HandleFunc("/resources/{resource}/{resourceId}/diagnostics", func(w http.ResponseWriter, r *http.Request) {
+		api.GetResourceDiagnostics(w, r, contrl)
+	}).Methods("GET")
+	r.HandleFunc("/resources/{resource}/{resourceId}/history", func(w http.ResponseWriter, r *http.Request) {
+		api.GetHistory(w, r, contrl)
+	}).Methods("GET")
+	r.HandleFunc("/resources/{resource}/{resourceId}/extend", func(w http.ResponseWriter, r *http.Request) {
+		api.ExtendResource(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/resources/{resource}/{resourceId}/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		api.TagResource(w, r, contrl)
+	}).Methods("PUT")
+	r.HandleFunc("/resources/{resource}/{resourceId}/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		api.UntagResource(w, r, contrl)
+	}).Methods("DELETE")
+	r.HandleFunc("/resources/{resource}/{resourceId}/owners", func(w http.ResponseWriter, r *http.Request) {
+		api.GetOwners(w, r, contrl)
+	}).Methods("GET")
+	r.HandleFunc("/resources/{resource}/{resourceId}/owners", func(w http.ResponseWriter, r *http.Request) {
+		api.PatchOwners(w, r, contrl)
+	}).Methods("PATCH")
+	r.HandleFunc("/admin/resources/{resource}/freeze", func(w http.ResponseWriter, r *http.Request) {
+		api.FreezeResource(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/admin/resources/{resource}/unfreeze", func(w http.ResponseWriter, r *http.Request) {
+		api.UnfreezeResource(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/admin/resources/{resource}/drain", func(w http.ResponseWriter, r *http.Request) {
+		api.DrainResource(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/admin/resources/{resource}/outdated", func(w http.ResponseWriter, r *http.Request) {
+		api.GetOutdatedInstances(w, r, contrl)
+	}).Methods("GET")
+	r.HandleFunc("/admin/resources/{resource}/recreate", func(w http.ResponseWriter, r *http.Request) {
+		api.RecreateOutdatedInstances(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/admin/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		api.SetMaintenance(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/admin/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		api.ResetMaintenance(w, r, contrl)
+	}).Methods("DELETE")
+	r.HandleFunc("/admin/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		api.GetMaintenance(w, r)
+	}).Methods("GET")
+	r.HandleFunc("/admin/clock", func(w http.ResponseWriter, r *http.Request) {
+		api.SetClock(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/admin/clock", func(w http.ResponseWriter, r *http.Request) {
+		api.ResetClock(w, r, contrl)
+	}).Methods("DELETE")
+	r.HandleFunc("/webhooks/vcs", func(w http.ResponseWriter, r *http.Request) {
+		api.VCSWebhook(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/webhooks/identity", func(w http.ResponseWriter, r *http.Request) {
+		api.IdentityWebhook(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/webhooks/admission", func(w http.ResponseWriter, r *http.Request) {
+		api.AdmitNamespace(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/webhooks/admission/mutate", func(w http.ResponseWriter, r *http.Request) {
+		api.MutateWorkload(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/webhooks/slack/command", func(w http.ResponseWriter, r *http.Request) {
+		api.SlackCommand(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/webhooks/slack/interaction", func(w http.ResponseWriter, r *http.Request) {
+		api.SlackInteraction(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/budgets", func(w http.ResponseWriter, r *http.Request) {
+		api.GetBudgets(w, r, contrl)
+	}).Methods("GET")
+	r.HandleFunc("/extensions/pending", func(w http.ResponseWriter, r *http.Request) {
+		api.GetPendingExtensions(w, r, contrl)
+	}).Methods("GET")
+	r.HandleFunc("/extensions/{resourceId}/approve", func(w http.ResponseWriter, r *http.Request) {
+		api.ResolveExtension(w, r, contrl, true)
+	}).Methods("POST")
+	r.HandleFunc("/extensions/{resourceId}/deny", func(w http.ResponseWriter, r *http.Request) {
+		api.ResolveExtension(w, r, contrl, false)
+	}).Methods("POST")
+	r.HandleFunc("/resources/{resource}/{resourceId}/kubeconfig", func(w http.ResponseWriter, r *http.Request) {
+		api.GetResourceKubeconfig(w, r, contrl)
+	}).Methods("GET")
+	r.HandleFunc("/resources/{resource}/{resourceId}/outputs", func(w http.ResponseWriter, r *http.Request) {
+		api.GetResourceOutputs(w, r, contrl)
+	}).Methods("GET")
+	r.HandleFunc("/resources/{resource}/{resourceId}/faults", func(w http.ResponseWriter, r *http.Request) {
+		api.InjectFault(w, r, contrl)
+	}).Methods("POST")
+	r.HandleFunc("/resources/{resource}/{resourceId}/resources", func(w http.ResponseWriter, r *http.Request) {
+		api.ResizeResource(w, r, contrl)
+	}).Methods("PATCH")
+	http.Handle("/", tracing.Middleware(r))
 
-	go contrl.CleanupLoop(*cleanUpInterval)
+	go contrl.CleanupLoop(cleanUpInterval)
+	go contrl.PoolLoop(cleanUpInterval)
+	go contrl.SuspendLoop(cleanUpInterval)
+	go contrl.InventoryLoop()
+	go contrl.ShardLoop()
+	go contrl.IdentitySyncLoop()
+}
 
-	log.Println("Serving api on:", *httpListenPort)
-	log.Fatal(http.ListenAndServe(":" + *httpListenPort, nil))
+// runConvert converts a docker-compose file into a draft resource definition
+// printed on stdout
+func runConvert(composePath string, resourceName string) {
+	data, err := ioutil.ReadFile(composePath)
+	if err != nil {
+		log.Fatalf("error while reading %s: %v", composePath, err)
+	}
+	draft, err := controller.ConvertCompose(data, resourceName)
+	if err != nil {
+		log.Fatalf("error while converting %s: %v", composePath, err)
+	}
+	os.Stdout.WriteString(draft)
 }
 
+// runWatch starts the stateful clusters watcher, one per kubeconfig context
+// when some are given, a single one on the default client otherwise
+func runWatch(kubeClient *kubernetes.Clientset, clientOpts kube.Options, contexts []string, namespacePattern string, gracePeriod time.Duration, maxKillsPerHour int, cooldown time.Duration, checkInterval time.Duration, retaliations []string, excludeSelector string, excludeOwnerKinds []string, retaliateOn []string, alertWebhook string, maxKillsPerPod int, persistKillHistory bool, checkJitter time.Duration, maintenanceToken string) {
+	rules, err := watcher.ParseActionRules(retaliations)
+	if err != nil {
+		log.Fatalf("error while parsing retaliation rules: %v", err)
+	}
+
+	defaultPolicy := watcher.Policy{
+		GracePeriod:       gracePeriod,
+		MaxKillsPerHour:   maxKillsPerHour,
+		Cooldown:          cooldown,
+		ExcludeSelector:   excludeSelector,
+		ExcludeOwnerKinds: excludeOwnerKinds,
+		RetaliateOn:       retaliateOn,
+		MaxKillsPerPod:    maxKillsPerPod,
+	}
+
+	watchers := []*watcher.Watcher{}
+	if len(contexts) == 0 {
+		watchers = append(watchers, watcher.NewWatcher(kubeClient, "default", namespacePattern, defaultPolicy, rules, alertWebhook, persistKillHistory))
+	} else {
+		for _, context := range contexts {
+			contextOpts := clientOpts
+			contextOpts.Context = context
+			contextOpts.InCluster = false
+			client, err := kube.NewClient(contextOpts)
+			if err != nil {
+				log.Fatalf("Cannot create the kube client driver for context %s: %v", context, err)
+			}
+			watchers = append(watchers, watcher.NewWatcher(client, context, namespacePattern, defaultPolicy, rules, alertWebhook, persistKillHistory))
+		}
+	}
+
+	http.HandleFunc("/clusters", func(rw http.ResponseWriter, r *http.Request) {
+		watcher.GetClusters(rw, r, watchers)
+	})
+	http.HandleFunc("/would-kill", func(rw http.ResponseWriter, r *http.Request) {
+		watcher.GetWouldKill(rw, r, watchers)
+	})
+	http.HandleFunc("/admin/maintenance", func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			api.SetMaintenanceWithToken(rw, r, maintenanceToken)
+		case http.MethodDelete:
+			api.ResetMaintenanceWithToken(rw, r, maintenanceToken)
+		default:
+			api.GetMaintenance(rw, r)
+		}
+	})
+	// Three missed cycles mean the watch is stuck, not just slow
+	staleAfter := 3 * (checkInterval + checkJitter)
+	http.HandleFunc("/healthz", func(rw http.ResponseWriter, r *http.Request) {
+		watcher.Healthz(rw, r, watchers, staleAfter)
+	})
+	http.HandleFunc("/readyz", func(rw http.ResponseWriter, r *http.Request) {
+		watcher.Readyz(rw, r, watchers)
+	})
+
+	for _, w := range watchers {
+		log.Printf("Watching namespaces matching %s on cluster %s", namespacePattern, w.Cluster())
+		go w.WatchLoop(checkInterval, checkJitter)
+	}
+}
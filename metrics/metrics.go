@@ -0,0 +1,279 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry holds metrics and exposes them in the Prometheus text format
+type Registry struct {
+	mutex   sync.Mutex
+	metrics []vector
+}
+
+type vector interface {
+	write(w http.ResponseWriter)
+}
+
+var defaultRegistry = &Registry{}
+
+// Handler returns an http handler serving the metrics of the default registry
+func Handler() http.Handler {
+	return defaultRegistry
+}
+
+// ServeHTTP writes every registered metric in the Prometheus text format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, metric := range r.metrics {
+		metric.write(w)
+	}
+}
+
+func (r *Registry) register(metric vector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.metrics = append(r.metrics, metric)
+}
+
+// CounterVec is a counter metric partitioned by labels
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+	mutex  sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec returns a counter registered on the default registry
+func NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	counter := &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+	defaultRegistry.register(counter)
+	return counter
+}
+
+// WithLabelValues returns a handle on the serie for the given label values
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	return &Counter{vec: c, key: labelKey(c.labels, values)}
+}
+
+func (c *CounterVec) write(w http.ResponseWriter) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	writeSeries(w, c.name, c.help, "counter", c.values)
+}
+
+// Counter is a single serie of a CounterVec
+type Counter struct {
+	vec *CounterVec
+	key string
+}
+
+// Inc increments the counter by 1
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increments the counter by @value
+func (c *Counter) Add(value float64) {
+	c.vec.mutex.Lock()
+	defer c.vec.mutex.Unlock()
+	c.vec.values[c.key] += value
+}
+
+// GaugeVec is a gauge metric partitioned by labels
+type GaugeVec struct {
+	name   string
+	help   string
+	labels []string
+	mutex  sync.Mutex
+	values map[string]float64
+}
+
+// NewGaugeVec returns a gauge registered on the default registry
+func NewGaugeVec(name string, help string, labels ...string) *GaugeVec {
+	gauge := &GaugeVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+	defaultRegistry.register(gauge)
+	return gauge
+}
+
+// WithLabelValues returns a handle on the serie for the given label values
+func (g *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return &Gauge{vec: g, key: labelKey(g.labels, values)}
+}
+
+// DeleteLabelValues removes the serie for the given label values
+func (g *GaugeVec) DeleteLabelValues(values ...string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.values, labelKey(g.labels, values))
+}
+
+func (g *GaugeVec) write(w http.ResponseWriter) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	writeSeries(w, g.name, g.help, "gauge", g.values)
+}
+
+// Gauge is a single serie of a GaugeVec
+type Gauge struct {
+	vec *GaugeVec
+	key string
+}
+
+// Set sets the gauge to @value
+func (g *Gauge) Set(value float64) {
+	g.vec.mutex.Lock()
+	defer g.vec.mutex.Unlock()
+	g.vec.values[g.key] = value
+}
+
+// Inc increments the gauge by 1
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec decrements the gauge by 1
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Add increments the gauge by @value
+func (g *Gauge) Add(value float64) {
+	g.vec.mutex.Lock()
+	defer g.vec.mutex.Unlock()
+	g.vec.values[g.key] += value
+}
+
+// HistogramVec is a histogram metric partitioned by labels
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	mutex   sync.Mutex
+	counts  map[string][]float64
+	sums    map[string]float64
+	totals  map[string]float64
+}
+
+// NewHistogramVec returns a histogram registered on the default registry.
+// @buckets are the upper bounds of the buckets, in increasing order
+func NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	histogram := &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		counts:  make(map[string][]float64),
+		sums:    make(map[string]float64),
+		totals:  make(map[string]float64),
+	}
+	defaultRegistry.register(histogram)
+	return histogram
+}
+
+// WithLabelValues returns a handle on the serie for the given label values
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return &Histogram{vec: h, key: labelKey(h.labels, values)}
+}
+
+func (h *HistogramVec) write(w http.ResponseWriter) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n", h.name, h.help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", h.name)
+	keys := make([]string, 0, len(h.counts))
+	for key := range h.counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		cumulative := float64(0)
+		for i, bound := range h.buckets {
+			cumulative += h.counts[key][i]
+			fmt.Fprintf(w, "%s_bucket%s %v\n", h.name, bucketKey(key, fmt.Sprintf("%v", bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %v\n", h.name, bucketKey(key, "+Inf"), h.totals[key])
+		fmt.Fprintf(w, "%s_sum%s %v\n", h.name, key, h.sums[key])
+		fmt.Fprintf(w, "%s_count%s %v\n", h.name, key, h.totals[key])
+	}
+}
+
+// Histogram is a single serie of a HistogramVec
+type Histogram struct {
+	vec *HistogramVec
+	key string
+}
+
+// Observe records one observation
+func (h *Histogram) Observe(value float64) {
+	h.vec.mutex.Lock()
+	defer h.vec.mutex.Unlock()
+	counts, ok := h.vec.counts[h.key]
+	if ok != true {
+		counts = make([]float64, len(h.vec.buckets))
+		h.vec.counts[h.key] = counts
+	}
+	for i, bound := range h.vec.buckets {
+		if value <= bound {
+			counts[i]++
+			break
+		}
+	}
+	h.vec.sums[h.key] += value
+	h.vec.totals[h.key]++
+}
+
+// bucketKey appends the le label to an already formatted label key
+func bucketKey(key string, le string) string {
+	if key == "" {
+		return fmt.Sprintf("{le=%q}", le)
+	}
+	return strings.TrimSuffix(key, "}") + fmt.Sprintf(",le=%q}", le)
+}
+
+// labelKey formats labels as they appear in the exposition format, like
+// {namespace="default"}, so series can be used as map keys directly
+func labelKey(labels []string, values []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(labels))
+	for i, label := range labels {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs[i] = fmt.Sprintf("%s=%q", label, value)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func writeSeries(w http.ResponseWriter, name string, help string, kind string, values map[string]float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %v\n", name, key, values[key])
+	}
+}
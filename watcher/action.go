@@ -0,0 +1,153 @@
+package watcher
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Action is a retaliation action executed against an unhealthy pod
+type Action interface {
+	Name() string
+	Execute(kubeClient *kubernetes.Clientset, namespace string, pod string) error
+}
+
+// ActionRule binds an action chain to a namespace pattern
+type ActionRule struct {
+	Pattern string
+	Actions []Action
+}
+
+// ParseActionRules parses rules like 'pattern=kill-pod,alert' into action rules.
+// When no rule is given, every namespace defaults to kill-pod.
+func ParseActionRules(rules []string) ([]ActionRule, error) {
+	if len(rules) == 0 {
+		rules = []string{"*=kill-pod"}
+	}
+	actionRules := []ActionRule{}
+	for _, rule := range rules {
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid rule %s, expected 'pattern=action,action'", rule)
+		}
+		actions := []Action{}
+		for _, name := range strings.Split(parts[1], ",") {
+			action, err := actionByName(strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, action)
+		}
+		actionRules = append(actionRules, ActionRule{Pattern: parts[0], Actions: actions})
+	}
+	return actionRules, nil
+}
+
+// FindActionChain returns the action chain of the first rule matching the namespace
+func FindActionChain(rules []ActionRule, namespace string) []Action {
+	for _, rule := range rules {
+		matched, err := path.Match(rule.Pattern, namespace)
+		if err != nil {
+			log.Println("Error:", err)
+			continue
+		}
+		if matched {
+			return rule.Actions
+		}
+	}
+	return []Action{}
+}
+
+// actionByName returns the action registered under a name
+func actionByName(name string) (Action, error) {
+	switch name {
+	case "kill-pod":
+		return killPodAction{}, nil
+	case "cordon-drain":
+		return cordonDrainAction{}, nil
+	case "restart-statefulset":
+		return restartStatefulSetAction{}, nil
+	case "annotate":
+		return annotatePodAction{}, nil
+	case "alert":
+		return alertAction{}, nil
+	}
+	return nil, fmt.Errorf("unknown action: %s", name)
+}
+
+// killPodAction deletes the unhealthy pod
+type killPodAction struct{}
+
+func (a killPodAction) Name() string { return "kill-pod" }
+
+func (a killPodAction) Execute(kubeClient *kubernetes.Clientset, namespace string, pod string) error {
+	return kubeClient.CoreV1().Pods(namespace).Delete(pod, nil)
+}
+
+// cordonDrainAction cordons the node hosting the pod then deletes the pod
+type cordonDrainAction struct{}
+
+func (a cordonDrainAction) Name() string { return "cordon-drain" }
+
+func (a cordonDrainAction) Execute(kubeClient *kubernetes.Clientset, namespace string, pod string) error {
+	kubePod, err := kubeClient.CoreV1().Pods(namespace).Get(pod, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if kubePod.Spec.NodeName == "" {
+		return fmt.Errorf("pod %s is not scheduled on any node", pod)
+	}
+	patch := []byte(`{"spec":{"unschedulable":true}}`)
+	_, err = kubeClient.CoreV1().Nodes().Patch(kubePod.Spec.NodeName, types.StrategicMergePatchType, patch)
+	if err != nil {
+		return err
+	}
+	return kubeClient.CoreV1().Pods(namespace).Delete(pod, nil)
+}
+
+// restartStatefulSetAction triggers a rollout of the StatefulSet owning the pod
+type restartStatefulSetAction struct{}
+
+func (a restartStatefulSetAction) Name() string { return "restart-statefulset" }
+
+func (a restartStatefulSetAction) Execute(kubeClient *kubernetes.Clientset, namespace string, pod string) error {
+	kubePod, err := kubeClient.CoreV1().Pods(namespace).Get(pod, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	for _, owner := range kubePod.OwnerReferences {
+		if owner.Kind == "StatefulSet" {
+			patch := []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"statefulmanager/restartedAt":"%s"}}}}}`, time.Now().Format(time.RFC3339)))
+			_, err = kubeClient.AppsV1().StatefulSets(namespace).Patch(owner.Name, types.StrategicMergePatchType, patch)
+			return err
+		}
+	}
+	return fmt.Errorf("pod %s is not owned by a StatefulSet", pod)
+}
+
+// annotatePodAction marks the pod with a retaliation annotation
+type annotatePodAction struct{}
+
+func (a annotatePodAction) Name() string { return "annotate" }
+
+func (a annotatePodAction) Execute(kubeClient *kubernetes.Clientset, namespace string, pod string) error {
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{"statefulmanager/unhealthy":"%s"}}}`, time.Now().Format(time.RFC3339)))
+	_, err := kubeClient.CoreV1().Pods(namespace).Patch(pod, types.StrategicMergePatchType, patch)
+	return err
+}
+
+// alertAction only logs, letting a human decide what to do
+type alertAction struct{}
+
+func (a alertAction) Name() string { return "alert" }
+
+func (a alertAction) Execute(kubeClient *kubernetes.Clientset, namespace string, pod string) error {
+	log.Printf("ALERT: pod %s/%s is unhealthy and needs attention", namespace, pod)
+	return nil
+}
@@ -0,0 +1,66 @@
+package watcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// preKillAlert posts to the alert webhook when a pod has been unhealthy for
+// half of the grace period, giving a human a chance to intervene before the
+// automated killing starts. The payload carries a ready-to-display text
+// field, so a Slack incoming webhook works out of the box, along with the
+// structured fields for PagerDuty-style routers
+func (w *Watcher) preKillAlert(namespace string, pod string, streak time.Duration, gracePeriod time.Duration) {
+	payload := map[string]interface{}{
+		"text":        fmt.Sprintf("pod %s/%s has been unhealthy for %s, retaliation in %s unless it recovers", namespace, pod, streak.Round(time.Second), (gracePeriod - streak).Round(time.Second)),
+		"namespace":   namespace,
+		"pod":         pod,
+		"unhealthy":   streak.String(),
+		"gracePeriod": gracePeriod.String(),
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	response, err := http.Post(w.alertWebhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		log.Printf("Error: alert webhook answered %s", response.Status)
+		return
+	}
+	alertsTotal.WithLabelValues(w.cluster, namespace).Inc()
+}
+
+// killLoopAlert tells the alert webhook that a pod hit its kill cap and the
+// watcher stopped retaliating against it
+func (w *Watcher) killLoopAlert(namespace string, pod string, kills int) {
+	payload := map[string]interface{}{
+		"text":      fmt.Sprintf("pod %s/%s was killed %d times in the last hour, retaliation stopped: it needs a human", namespace, pod, kills),
+		"namespace": namespace,
+		"pod":       pod,
+		"kills":     kills,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	response, err := http.Post(w.alertWebhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		log.Printf("Error: alert webhook answered %s", response.Status)
+	}
+}
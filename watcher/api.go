@@ -0,0 +1,63 @@
+package watcher
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// GetClusters displays the health state of every watched cluster, keyed by
+// the cluster name of each watcher
+func GetClusters(w http.ResponseWriter, r *http.Request, watchers []*Watcher) {
+	snapshot := map[string]map[string]ClusterHealth{}
+	for _, watcher := range watchers {
+		snapshot[watcher.cluster] = watcher.ClustersSnapshot()
+	}
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// GetWouldKill displays, per cluster and namespace, what the retaliation
+// logic would do right now: which pods it would kill and which ones are
+// still inside their grace period, cooldown or kill caps
+func GetWouldKill(w http.ResponseWriter, r *http.Request, watchers []*Watcher) {
+	report := map[string]map[string][]KillPlan{}
+	for _, watcher := range watchers {
+		report[watcher.cluster] = watcher.WouldKill()
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// Healthz answers 200 while every watcher completed an evaluation cycle less
+// than @staleAfter ago and 503 otherwise, so a stuck watch gets its process
+// restarted by the liveness probe instead of silently doing nothing
+func Healthz(w http.ResponseWriter, r *http.Request, watchers []*Watcher, staleAfter time.Duration) {
+	for _, watcher := range watchers {
+		if watcher.Alive(staleAfter) != true {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]string{"status": "stuck", "cluster": watcher.cluster})
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// Readyz answers 200 once every watcher completed its first evaluation
+// cycle, along with the last evaluation timestamp of each watched namespace
+func Readyz(w http.ResponseWriter, r *http.Request, watchers []*Watcher) {
+	ready := true
+	evaluations := map[string]map[string]time.Time{}
+	for _, watcher := range watchers {
+		if watcher.Ready() != true {
+			ready = false
+		}
+		namespaces := map[string]time.Time{}
+		for namespace, cluster := range watcher.ClustersSnapshot() {
+			namespaces[namespace] = cluster.LastEvaluated
+		}
+		evaluations[watcher.cluster] = namespaces
+	}
+	if ready != true {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(evaluations)
+}
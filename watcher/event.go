@@ -0,0 +1,46 @@
+package watcher
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// recordRetaliation leaves a trail in the cluster when a pod is retaliated
+// against: a Warning Event on the pod and an annotation on the namespace, so
+// an operator understands why the pod vanished without digging through the
+// manager logs
+func (w *Watcher) recordRetaliation(namespace string, pod string, actions []string) {
+	now := metav1.Now()
+	event := &apiv1.Event{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "statefulmanager-", Namespace: namespace},
+		InvolvedObject: apiv1.ObjectReference{
+			Kind:      "Pod",
+			Namespace: namespace,
+			Name:      pod,
+		},
+		Reason:         "Retaliation",
+		Message:        fmt.Sprintf("statefulmanager retaliated against %s after its unhealthy grace period (actions: %s)", pod, strings.Join(actions, ",")),
+		Type:           apiv1.EventTypeWarning,
+		Source:         apiv1.EventSource{Component: "statefulmanager"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	_, err := w.kubeClient.CoreV1().Events(namespace).Create(event)
+	if err != nil {
+		log.Println("Error:", err)
+	}
+
+	value := fmt.Sprintf("%s at %s (actions: %s)", pod, time.Now().Format(time.RFC3339), strings.Join(actions, ","))
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{"statefulmanager/last-retaliation":%q}}}`, value))
+	_, err = w.kubeClient.CoreV1().Namespaces().Patch(namespace, types.StrategicMergePatchType, patch)
+	if err != nil {
+		log.Println("Error:", err)
+	}
+}
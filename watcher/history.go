@@ -0,0 +1,80 @@
+package watcher
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/geobeau/k8s-ephemeral-resources/clock"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// killHistoryConfigMap persists the kill history of a namespace, so a
+// restart of the watcher does not reset the per-pod kill caps
+const killHistoryConfigMap = "statefulmanager-kill-history"
+
+// podKillsInLastHour returns the number of kills of one pod in the last
+// rolling hour
+func (c *ClusterHealth) podKillsInLastHour(pod string) int {
+	count := 0
+	for _, killTime := range c.podKills[pod] {
+		if clock.Since(killTime) < time.Hour {
+			count++
+		}
+	}
+	return count
+}
+
+// recordKill appends a kill to the history of a pod, dropping the entries
+// older than the rolling hour
+func (c *ClusterHealth) recordKill(pod string) {
+	kills := []time.Time{}
+	for _, killTime := range c.podKills[pod] {
+		if clock.Since(killTime) < time.Hour {
+			kills = append(kills, killTime)
+		}
+	}
+	c.podKills[pod] = append(kills, clock.Now())
+}
+
+// loadKillHistory restores the kill history of a namespace from its
+// ConfigMap, best effort
+func (w *Watcher) loadKillHistory(namespace string, cluster *ClusterHealth) {
+	configMap, err := w.kubeClient.CoreV1().ConfigMaps(namespace).Get(killHistoryConfigMap, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) != true {
+			log.Println("Error:", err)
+		}
+		return
+	}
+	history := map[string][]time.Time{}
+	err = json.Unmarshal([]byte(configMap.Data["history"]), &history)
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	cluster.podKills = history
+}
+
+// saveKillHistory persists the kill history of a namespace in its ConfigMap,
+// best effort
+func (w *Watcher) saveKillHistory(namespace string, cluster *ClusterHealth) {
+	history, err := json.Marshal(cluster.podKills)
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	configMap := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: killHistoryConfigMap, Namespace: namespace},
+		Data:       map[string]string{"history": string(history)},
+	}
+	_, err = w.kubeClient.CoreV1().ConfigMaps(namespace).Create(configMap)
+	if errors.IsAlreadyExists(err) {
+		_, err = w.kubeClient.CoreV1().ConfigMaps(namespace).Update(configMap)
+	}
+	if err != nil {
+		log.Println("Error:", err)
+	}
+}
@@ -0,0 +1,35 @@
+package watcher
+
+import (
+	"github.com/geobeau/k8s-ephemeral-resources/metrics"
+)
+
+var (
+	killsTotal = metrics.NewCounterVec("statefulmanager_kills_total",
+		"Number of retaliations performed per namespace", "cluster", "namespace")
+
+	budgetExhaustedTotal = metrics.NewCounterVec("statefulmanager_budget_exhausted_total",
+		"Number of retaliations skipped because the kill budget was exhausted", "cluster", "namespace")
+
+	cooldownSkipsTotal = metrics.NewCounterVec("statefulmanager_cooldown_skips_total",
+		"Number of retaliations skipped because a cooldown was in progress", "cluster", "namespace")
+
+	clusterHealthy = metrics.NewGaugeVec("statefulmanager_cluster_healthy",
+		"1 if every pod of the watched namespace is healthy, 0 otherwise", "cluster", "namespace")
+
+	clusterStateGauge = metrics.NewGaugeVec("statefulmanager_cluster_state",
+		"1 for the current state of the watched namespace, 0 for the others", "cluster", "namespace", "state")
+
+	rolloutHoldsTotal = metrics.NewCounterVec("statefulmanager_rollout_holds_total",
+		"Number of retaliation rounds skipped because a rollout was in progress", "cluster", "namespace")
+
+	alertsTotal = metrics.NewCounterVec("statefulmanager_pre_kill_alerts_total",
+		"Number of pre-kill alerts sent per namespace", "cluster", "namespace")
+
+	killLoopsTotal = metrics.NewCounterVec("statefulmanager_kill_loops_total",
+		"Number of pods whose retaliation was stopped by the per-pod kill cap", "cluster", "namespace")
+
+	unhealthyDuration = metrics.NewHistogramVec("statefulmanager_unhealthy_duration_seconds",
+		"Duration a cluster stayed unhealthy before recovering or being retaliated against",
+		[]float64{30, 60, 120, 300, 600, 1800, 3600}, "cluster", "namespace", "outcome")
+)
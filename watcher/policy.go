@@ -0,0 +1,147 @@
+package watcher
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Policy is the retaliation policy applied to one namespace
+type Policy struct {
+	GracePeriod       time.Duration
+	MaxKillsPerHour   int
+	Cooldown          time.Duration
+	Disabled          bool
+	ExcludeSelector   string
+	ExcludeOwnerKinds []string
+	RetaliateOn       []string
+	MaxKillsPerPod    int
+}
+
+// retaliatesOn returns true when the policy targets the state. Without an
+// explicit list only DEGRADED and FAILED are targeted: a cluster still
+// rolling out is not a reason to kill pods
+func (p *Policy) retaliatesOn(state string) bool {
+	states := p.RetaliateOn
+	if len(states) == 0 {
+		states = []string{StateDegraded, StateFailed}
+	}
+	for _, candidate := range states {
+		if strings.EqualFold(candidate, state) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludes returns true when a pod must never enter the health tracking:
+// either its labels match the exclusion selector or it is owned by an
+// excluded kind. A Job pod legitimately Pending during queueing is not a
+// reason to retaliate
+func (p *Policy) excludes(pod *apiv1.Pod) bool {
+	if p.ExcludeSelector != "" {
+		selector, err := labels.Parse(p.ExcludeSelector)
+		if err != nil {
+			log.Printf("Ignoring exclusion selector %q: %v", p.ExcludeSelector, err)
+		} else if selector.Matches(labels.Set(pod.Labels)) {
+			return true
+		}
+	}
+	for _, owner := range pod.OwnerReferences {
+		for _, kind := range p.ExcludeOwnerKinds {
+			if strings.EqualFold(owner.Kind, kind) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PolicyFromNamespace returns the policy of a namespace, starting from the
+// global policy and overriding it with the statefulmanager/* annotations
+func PolicyFromNamespace(defaults Policy, namespace *apiv1.Namespace) Policy {
+	policy := defaults
+
+	gracePeriod, ok := namespace.Annotations["statefulmanager/grace-period"]
+	if ok {
+		duration, err := time.ParseDuration(gracePeriod)
+		if err != nil {
+			log.Printf("Ignoring annotation statefulmanager/grace-period on %s: %v", namespace.Name, err)
+		} else {
+			policy.GracePeriod = duration
+		}
+	}
+
+	maxKills, ok := namespace.Annotations["statefulmanager/max-kills-per-hour"]
+	if ok {
+		max, err := strconv.Atoi(maxKills)
+		if err != nil {
+			log.Printf("Ignoring annotation statefulmanager/max-kills-per-hour on %s: %v", namespace.Name, err)
+		} else {
+			policy.MaxKillsPerHour = max
+		}
+	}
+
+	cooldown, ok := namespace.Annotations["statefulmanager/cooldown"]
+	if ok {
+		duration, err := time.ParseDuration(cooldown)
+		if err != nil {
+			log.Printf("Ignoring annotation statefulmanager/cooldown on %s: %v", namespace.Name, err)
+		} else {
+			policy.Cooldown = duration
+		}
+	}
+
+	maxKillsPerPod, ok := namespace.Annotations["statefulmanager/max-kills-per-pod"]
+	if ok {
+		max, err := strconv.Atoi(maxKillsPerPod)
+		if err != nil {
+			log.Printf("Ignoring annotation statefulmanager/max-kills-per-pod on %s: %v", namespace.Name, err)
+		} else {
+			policy.MaxKillsPerPod = max
+		}
+	}
+
+	excludeSelector, ok := namespace.Annotations["statefulmanager/exclude-selector"]
+	if ok {
+		policy.ExcludeSelector = excludeSelector
+	}
+
+	excludeOwnerKinds, ok := namespace.Annotations["statefulmanager/exclude-owner-kinds"]
+	if ok {
+		kinds := []string{}
+		for _, kind := range strings.Split(excludeOwnerKinds, ",") {
+			if kind != "" {
+				kinds = append(kinds, kind)
+			}
+		}
+		policy.ExcludeOwnerKinds = kinds
+	}
+
+	retaliateOn, ok := namespace.Annotations["statefulmanager/retaliate-on"]
+	if ok {
+		states := []string{}
+		for _, state := range strings.Split(retaliateOn, ",") {
+			if state != "" {
+				states = append(states, state)
+			}
+		}
+		policy.RetaliateOn = states
+	}
+
+	disabled, ok := namespace.Annotations["statefulmanager/disabled"]
+	if ok {
+		value, err := strconv.ParseBool(disabled)
+		if err != nil {
+			log.Printf("Ignoring annotation statefulmanager/disabled on %s: %v", namespace.Name, err)
+		} else {
+			policy.Disabled = value
+		}
+	}
+
+	return policy
+}
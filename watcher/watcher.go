@@ -0,0 +1,531 @@
+package watcher
+
+import (
+	"log"
+	"math/rand"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/geobeau/k8s-ephemeral-resources/clock"
+	"github.com/geobeau/k8s-ephemeral-resources/maintenance"
+	"k8s.io/client-go/kubernetes"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Cluster states, from healthy to failed. DEGRADED is a Running pod that is
+// not ready, PENDING_ROLLOUT a pod still being scheduled or pulled, FAILED a
+// pod whose phase or containers are beyond recovery
+const (
+	StateHealthy        = "HEALTHY"
+	StatePendingRollout = "PENDING_ROLLOUT"
+	StateDegraded       = "DEGRADED"
+	StateFailed         = "FAILED"
+)
+
+var allStates = []string{StateHealthy, StatePendingRollout, StateDegraded, StateFailed}
+
+var stateRank = map[string]int{
+	StateHealthy:        0,
+	StatePendingRollout: 1,
+	StateDegraded:       2,
+	StateFailed:         3,
+}
+
+// ClusterHealth is the health state of one watched namespace
+type ClusterHealth struct {
+	Healthy        bool
+	State          string
+	Since          time.Time
+	UnhealthyPods  []string
+	UnhealthySince map[string]time.Time
+	LastEvaluated  time.Time
+	Kills          int
+	killTimes      []time.Time
+	alerted        map[string]bool
+	podKills       map[string][]time.Time
+	loopAlerted    map[string]bool
+	policy         Policy
+}
+
+// Watcher watches namespaces and retaliates against unhealthy clusters
+type Watcher struct {
+	kubeClient       *kubernetes.Clientset
+	cluster          string
+	namespacePattern string
+	defaultPolicy    Policy
+	mutex            sync.Mutex
+	clusters         map[string]*ClusterHealth
+	actionRules      []ActionRule
+	alertWebhook     string
+	persistHistory   bool
+	started          time.Time
+	lastCycle        time.Time
+}
+
+// NewWatcher returns a new watcher for namespaces matching pattern. @cluster
+// names the Kubernetes cluster behind the client and labels the metrics, so
+// several watchers can cover the clusters our namespace convention spans
+func NewWatcher(kubeClient *kubernetes.Clientset, cluster string, namespacePattern string, defaultPolicy Policy, actionRules []ActionRule, alertWebhook string, persistHistory bool) *Watcher {
+	return &Watcher{
+		kubeClient:       kubeClient,
+		cluster:          cluster,
+		namespacePattern: namespacePattern,
+		defaultPolicy:    defaultPolicy,
+		clusters:         make(map[string]*ClusterHealth),
+		actionRules:      actionRules,
+		alertWebhook:     alertWebhook,
+		persistHistory:   persistHistory,
+		started:          time.Now(),
+	}
+}
+
+// WatchLoop wakes up every @delay, plus a random jitter, to check the health
+// of watched clusters. The jitter is also applied between two namespaces, so
+// hundreds of watched clusters do not synchronize their evaluation and api
+// calls
+func (w *Watcher) WatchLoop(delay time.Duration, jitter time.Duration) {
+	for {
+		if maintenance.Active() {
+			log.Println("Maintenance mode: skipping the watch cycle")
+			// The watcher is paused on purpose, not stuck: keep the
+			// liveness probe green
+			w.mutex.Lock()
+			w.lastCycle = time.Now()
+			w.mutex.Unlock()
+			time.Sleep(delay)
+			continue
+		}
+		list, err := w.kubeClient.CoreV1().Namespaces().List(metav1.ListOptions{})
+		if err != nil {
+			log.Println("Error:", err)
+			time.Sleep(delay)
+			continue
+		}
+		for _, namespace := range list.Items {
+			matched, err := path.Match(w.namespacePattern, namespace.Name)
+			if err != nil {
+				log.Println("Error:", err)
+				break
+			}
+			if matched != true {
+				continue
+			}
+			time.Sleep(jitterDuration(jitter))
+			w.checkCluster(&namespace)
+		}
+		w.mutex.Lock()
+		w.lastCycle = time.Now()
+		w.mutex.Unlock()
+		time.Sleep(delay + jitterDuration(jitter))
+	}
+}
+
+// jitterDuration returns a random duration between 0 and @max
+func jitterDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// checkCluster checks the pods of a namespace and retaliates if the cluster
+// stayed unhealthy for longer than the grace period
+func (w *Watcher) checkCluster(kubeNamespace *apiv1.Namespace) {
+	namespace := kubeNamespace.Name
+	// A namespace being deleted takes its pods with it: nothing to retaliate
+	// against
+	if kubeNamespace.Status.Phase == apiv1.NamespaceTerminating || kubeNamespace.DeletionTimestamp != nil {
+		return
+	}
+	policy := PolicyFromNamespace(w.defaultPolicy, kubeNamespace)
+	if policy.Disabled {
+		return
+	}
+
+	pods, err := w.kubeClient.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+
+	unhealthyPods := []string{}
+	clusterState := StateHealthy
+	for _, pod := range pods.Items {
+		// A Terminating pod is already on its way out, killing it again is
+		// pointless
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if policy.excludes(&pod) {
+			continue
+		}
+		state := podState(&pod)
+		if stateRank[state] > stateRank[clusterState] {
+			clusterState = state
+		}
+		if state != StateHealthy && policy.retaliatesOn(state) {
+			unhealthyPods = append(unhealthyPods, pod.Name)
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	cluster, ok := w.clusters[namespace]
+	if ok != true {
+		cluster = &ClusterHealth{
+			Healthy:        true,
+			Since:          clock.Now(),
+			UnhealthySince: make(map[string]time.Time),
+			alerted:        make(map[string]bool),
+			podKills:       make(map[string][]time.Time),
+			loopAlerted:    make(map[string]bool),
+		}
+		if w.persistHistory {
+			w.loadKillHistory(namespace, cluster)
+		}
+		w.clusters[namespace] = cluster
+	}
+
+	// Track the unhealthy streak of each pod individually, so a cluster
+	// flapping between different unhealthy pods cannot dodge retaliation
+	for _, pod := range unhealthyPods {
+		_, ok := cluster.UnhealthySince[pod]
+		if ok != true {
+			cluster.UnhealthySince[pod] = clock.Now()
+		}
+	}
+	for pod := range cluster.UnhealthySince {
+		if containsString(unhealthyPods, pod) != true {
+			delete(cluster.UnhealthySince, pod)
+			delete(cluster.alerted, pod)
+		}
+	}
+
+	healthy := len(unhealthyPods) == 0
+	if healthy != cluster.Healthy {
+		if healthy {
+			unhealthyDuration.WithLabelValues(w.cluster, namespace, "recovered").Observe(clock.Since(cluster.Since).Seconds())
+		}
+		cluster.Healthy = healthy
+		cluster.Since = clock.Now()
+	}
+	cluster.UnhealthyPods = unhealthyPods
+	cluster.State = clusterState
+	cluster.LastEvaluated = time.Now()
+	cluster.policy = policy
+
+	for _, state := range allStates {
+		value := float64(0)
+		if state == clusterState {
+			value = 1
+		}
+		clusterStateGauge.WithLabelValues(w.cluster, namespace, state).Set(value)
+	}
+
+	if cluster.Healthy {
+		clusterHealthy.WithLabelValues(w.cluster, namespace).Set(1)
+		return
+	}
+	clusterHealthy.WithLabelValues(w.cluster, namespace).Set(0)
+
+	log.Printf("%s is unhealthy since %s (%d unhealthy pods)", namespace, cluster.Since, len(cluster.UnhealthyPods))
+
+	// A rolling update legitimately churns pods: hold fire until the owning
+	// workloads converged instead of killing pods mid-rollout
+	if w.rolloutInProgress(namespace) {
+		log.Printf("Holding fire on %s: a rollout is in progress", namespace)
+		rolloutHoldsTotal.WithLabelValues(w.cluster, namespace).Inc()
+		return
+	}
+
+	for _, pod := range cluster.UnhealthyPods {
+		streak := clock.Since(cluster.UnhealthySince[pod])
+		if w.alertWebhook != "" && streak >= policy.GracePeriod/2 && cluster.alerted[pod] != true {
+			cluster.alerted[pod] = true
+			go w.preKillAlert(namespace, pod, streak, policy.GracePeriod)
+		}
+		if streak < policy.GracePeriod {
+			continue
+		}
+
+		if policy.Cooldown > 0 && len(cluster.killTimes) > 0 {
+			lastKill := cluster.killTimes[len(cluster.killTimes)-1]
+			if clock.Since(lastKill) < policy.Cooldown {
+				log.Printf("Refusing to retaliate on %s: cooldown until %s", namespace, lastKill.Add(policy.Cooldown))
+				cooldownSkipsTotal.WithLabelValues(w.cluster, namespace).Inc()
+				continue
+			}
+		}
+
+		if policy.MaxKillsPerHour > 0 && cluster.killsInLastHour() >= policy.MaxKillsPerHour {
+			log.Printf("Refusing to retaliate on %s: kill budget of %d per hour exhausted", namespace, policy.MaxKillsPerHour)
+			budgetExhaustedTotal.WithLabelValues(w.cluster, namespace).Inc()
+			continue
+		}
+
+		// A pod getting killed over and over is genuinely broken: endless
+		// kill loops help nobody, stop and call a human instead
+		if policy.MaxKillsPerPod > 0 && cluster.podKillsInLastHour(pod) >= policy.MaxKillsPerPod {
+			if cluster.loopAlerted[pod] != true {
+				cluster.loopAlerted[pod] = true
+				log.Printf("Refusing to retaliate on %s/%s: killed %d times in the last hour, a human needs to look at it", namespace, pod, policy.MaxKillsPerPod)
+				killLoopsTotal.WithLabelValues(w.cluster, namespace).Inc()
+				if w.alertWebhook != "" {
+					go w.killLoopAlert(namespace, pod, policy.MaxKillsPerPod)
+				}
+			}
+			continue
+		}
+
+		if w.canRetaliate(namespace, pod) != true {
+			continue
+		}
+
+		w.retaliate(namespace, pod)
+		unhealthyDuration.WithLabelValues(w.cluster, namespace, "retaliated").Observe(streak.Seconds())
+		cluster.Kills++
+		cluster.killTimes = append(cluster.killTimes, clock.Now())
+		cluster.recordKill(pod)
+		if w.persistHistory {
+			w.saveKillHistory(namespace, cluster)
+		}
+		delete(cluster.UnhealthySince, pod)
+		delete(cluster.alerted, pod)
+		killsTotal.WithLabelValues(w.cluster, namespace).Inc()
+	}
+}
+
+// rolloutInProgress returns true while a Deployment or StatefulSet of the
+// namespace has not converged on its latest generation yet
+func (w *Watcher) rolloutInProgress(namespace string) bool {
+	deployments, err := w.kubeClient.AppsV1().Deployments(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return false
+	}
+	for _, deployment := range deployments.Items {
+		desired := int32(1)
+		if deployment.Spec.Replicas != nil {
+			desired = *deployment.Spec.Replicas
+		}
+		if deployment.Generation > deployment.Status.ObservedGeneration ||
+			deployment.Status.UpdatedReplicas < desired ||
+			deployment.Status.Replicas > deployment.Status.UpdatedReplicas {
+			return true
+		}
+	}
+
+	statefulSets, err := w.kubeClient.AppsV1().StatefulSets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return false
+	}
+	for _, statefulSet := range statefulSets.Items {
+		if statefulSet.Generation > statefulSet.Status.ObservedGeneration ||
+			statefulSet.Status.UpdateRevision != statefulSet.Status.CurrentRevision {
+			return true
+		}
+	}
+	return false
+}
+
+// canRetaliate verifies that disrupting the pod would not violate a
+// PodDisruptionBudget or take down the quorum of its StatefulSet
+func (w *Watcher) canRetaliate(namespace string, podName string) bool {
+	pod, err := w.kubeClient.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return false
+	}
+
+	pdbs, err := w.kubeClient.PolicyV1beta1().PodDisruptionBudgets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		log.Println("Error:", err)
+		return false
+	}
+	for _, pdb := range pdbs.Items {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			log.Println("Error:", err)
+			continue
+		}
+		if selector.Matches(labels.Set(pod.Labels)) && pdb.Status.PodDisruptionsAllowed < 1 {
+			log.Printf("Refusing to retaliate on %s/%s: PodDisruptionBudget %s allows no disruption", namespace, podName, pdb.Name)
+			return false
+		}
+	}
+
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind != "StatefulSet" {
+			continue
+		}
+		statefulSet, err := w.kubeClient.AppsV1().StatefulSets(namespace).Get(owner.Name, metav1.GetOptions{})
+		if err != nil {
+			log.Println("Error:", err)
+			return false
+		}
+		if statefulSet.Spec.Replicas != nil && statefulSet.Status.ReadyReplicas < *statefulSet.Spec.Replicas-1 {
+			log.Printf("Refusing to retaliate on %s/%s: other replicas of StatefulSet %s are not ready", namespace, podName, owner.Name)
+			return false
+		}
+	}
+
+	return true
+}
+
+// retaliate runs the action chain matching the namespace against a pod
+func (w *Watcher) retaliate(namespace string, pod string) {
+	actions := FindActionChain(w.actionRules, namespace)
+	executed := []string{}
+	for _, action := range actions {
+		log.Printf("Retaliating on %s/%s with action: %s", namespace, pod, action.Name())
+		err := action.Execute(w.kubeClient, namespace, pod)
+		if err != nil {
+			log.Println("Error:", err)
+			break
+		}
+		executed = append(executed, action.Name())
+	}
+	if len(executed) > 0 {
+		w.recordRetaliation(namespace, pod, executed)
+	}
+}
+
+// ClustersSnapshot returns a copy of the health state of every watched cluster
+func (w *Watcher) ClustersSnapshot() map[string]ClusterHealth {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	snapshot := make(map[string]ClusterHealth)
+	for namespace, cluster := range w.clusters {
+		snapshot[namespace] = *cluster
+	}
+	return snapshot
+}
+
+// KillPlan is what the retaliation logic would decide for one pod right now
+type KillPlan struct {
+	Pod       string
+	Reason    string
+	Remaining string `json:",omitempty"`
+}
+
+// WouldKill returns, per namespace, what the retaliation logic would decide
+// right now without acting on it, based on the last evaluation
+func (w *Watcher) WouldKill() map[string][]KillPlan {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	plans := make(map[string][]KillPlan)
+	for namespace, cluster := range w.clusters {
+		policy := cluster.policy
+		for _, pod := range cluster.UnhealthyPods {
+			plan := KillPlan{Pod: pod}
+			streak := clock.Since(cluster.UnhealthySince[pod])
+			switch {
+			case streak < policy.GracePeriod:
+				plan.Reason = "waiting for the grace period"
+				plan.Remaining = (policy.GracePeriod - streak).Round(time.Second).String()
+			case policy.Cooldown > 0 && len(cluster.killTimes) > 0 && clock.Since(cluster.killTimes[len(cluster.killTimes)-1]) < policy.Cooldown:
+				plan.Reason = "cooldown in progress"
+				plan.Remaining = (policy.Cooldown - clock.Since(cluster.killTimes[len(cluster.killTimes)-1])).Round(time.Second).String()
+			case policy.MaxKillsPerHour > 0 && cluster.killsInLastHour() >= policy.MaxKillsPerHour:
+				plan.Reason = "kill budget exhausted"
+			case policy.MaxKillsPerPod > 0 && cluster.podKillsInLastHour(pod) >= policy.MaxKillsPerPod:
+				plan.Reason = "per-pod kill cap reached"
+			default:
+				plan.Reason = "would kill"
+			}
+			plans[namespace] = append(plans[namespace], plan)
+		}
+	}
+	return plans
+}
+
+// Cluster returns the name of the cluster the watcher is attached to
+func (w *Watcher) Cluster() string {
+	return w.cluster
+}
+
+// Alive returns true when the watcher completed a full evaluation cycle less
+// than @staleAfter ago. A watcher still waiting for its first cycle is
+// considered alive until its start ages past the threshold
+func (w *Watcher) Alive(staleAfter time.Duration) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	last := w.lastCycle
+	if last.IsZero() {
+		last = w.started
+	}
+	return time.Since(last) < staleAfter
+}
+
+// Ready returns true once the watcher completed its first evaluation cycle
+func (w *Watcher) Ready() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.lastCycle.IsZero() != true
+}
+
+// killsInLastHour returns the number of kills in the last rolling hour
+func (c *ClusterHealth) killsInLastHour() int {
+	count := 0
+	for _, killTime := range c.killTimes {
+		if clock.Since(killTime) < time.Hour {
+			count++
+		}
+	}
+	return count
+}
+
+// containsString returns true if @list contains @value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// podState classifies a pod. NotReady-but-Running, still-scheduling and
+// truly failed pods are different situations and the policy can target them
+// separately
+func podState(pod *apiv1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting == nil {
+			continue
+		}
+		reason := status.State.Waiting.Reason
+		if reason == "CrashLoopBackOff" || reason == "ImagePullBackOff" || reason == "ErrImagePull" {
+			return StateFailed
+		}
+	}
+	switch pod.Status.Phase {
+	case apiv1.PodFailed:
+		return StateFailed
+	case apiv1.PodPending:
+		return StatePendingRollout
+	case apiv1.PodSucceeded:
+		return StateHealthy
+	}
+	if isPodHealthy(pod) {
+		return StateHealthy
+	}
+	return StateDegraded
+}
+
+// isPodHealthy returns true if a pod is running and ready
+func isPodHealthy(pod *apiv1.Pod) bool {
+	if pod.Status.Phase != apiv1.PodRunning {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == apiv1.PodReady && condition.Status != apiv1.ConditionTrue {
+			return false
+		}
+	}
+	return true
+}